@@ -0,0 +1,92 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/retry"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// ApplyMode selects how the rendered resources are written to the API server
+type ApplyMode string
+
+const (
+	// ApplyModeUpdate creates missing resources and overwrites existing ones with a Get, Create or Update loop.
+	// It is the default.
+	ApplyModeUpdate ApplyMode = "update"
+	// ApplyModeServerSide uses server-side apply with FieldManager, so fields owned by other managers are preserved
+	ApplyModeServerSide ApplyMode = "server-side"
+	// FieldManager is the field manager native submit applies resources with
+	FieldManager = "native-submit"
+)
+
+// ServerSideApply applies the rendered object with FieldManager and reports whether it was created, changed or left
+// as it was. An existing object owned by a different Spark Application is refused unless ownership takeover is allowed.
+func ServerSideApply(ctx context.Context, kubeClient ctrlClient.Client, desired ctrlClient.Object, resource string, opts SubmitOptions) (controllerutil.OperationResult, error) {
+	existing := reflect.New(reflect.TypeOf(desired).Elem()).Interface().(ctrlClient.Object)
+	err := kubeClient.Get(ctx, ctrlClient.ObjectKeyFromObject(desired), existing)
+	exists := err == nil
+	if err != nil && !apiErrors.IsNotFound(err) {
+		return controllerutil.OperationResultNone, fmt.Errorf("error while retrieving %s %s: %w", resource, desired.GetName(), err)
+	}
+	if exists {
+		if err := CheckOwnership(existing, desired, resource, opts.AllowOwnershipTakeover); err != nil {
+			return controllerutil.OperationResultNone, err
+		}
+	}
+
+	if err := kubeClient.Patch(ctx, desired, ctrlClient.Apply, ctrlClient.FieldOwner(FieldManager), ctrlClient.ForceOwnership); err != nil {
+		return controllerutil.OperationResultNone, fmt.Errorf("error while applying %s %s: %w", resource, desired.GetName(), err)
+	}
+	switch {
+	case !exists:
+		return controllerutil.OperationResultCreated, nil
+	case existing.GetResourceVersion() == desired.GetResourceVersion():
+		return controllerutil.OperationResultNone, nil
+	default:
+		return controllerutil.OperationResultUpdated, nil
+	}
+}
+
+// CreateOrUpdate creates the rendered object, or overwrites an existing one with it, and reports which of the two
+// happened. With ApplyModeServerSide it is applied with ServerSideApply instead. An existing object owned by a
+// different Spark Application is refused unless ownership takeover is allowed.
+func CreateOrUpdate(ctx context.Context, kubeClient ctrlClient.Client, desired ctrlClient.Object, resource string, opts SubmitOptions) (controllerutil.OperationResult, error) {
+	if opts.ApplyMode == ApplyModeServerSide {
+		return ServerSideApply(ctx, kubeClient, desired, resource, opts)
+	}
+	operationResult := controllerutil.OperationResultNone
+	err := retry.OnError(retry.DefaultRetry, IsRetriableConflict, func() error {
+		existing := reflect.New(reflect.TypeOf(desired).Elem()).Interface().(ctrlClient.Object)
+		err := kubeClient.Get(ctx, ctrlClient.ObjectKeyFromObject(desired), existing)
+		if apiErrors.IsNotFound(err) {
+			if err := kubeClient.Create(ctx, desired); err != nil {
+				return fmt.Errorf("error while creating %s %s: %w", resource, desired.GetName(), err)
+			}
+			operationResult = controllerutil.OperationResultCreated
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error while retrieving %s %s: %w", resource, desired.GetName(), err)
+		}
+		if err := AdoptExisting(existing, desired, resource, opts.AllowOwnershipTakeover); err != nil {
+			return err
+		}
+		// The rendered object replaces the existing one, apart from the metadata merged by AdoptExisting
+		desired.SetLabels(existing.GetLabels())
+		desired.SetAnnotations(existing.GetAnnotations())
+		desired.SetOwnerReferences(existing.GetOwnerReferences())
+		desired.SetFinalizers(existing.GetFinalizers())
+		desired.SetResourceVersion(existing.GetResourceVersion())
+		if err := kubeClient.Update(ctx, desired); err != nil {
+			return fmt.Errorf("error while updating %s %s: %w", resource, desired.GetName(), err)
+		}
+		operationResult = controllerutil.OperationResultUpdated
+		return nil
+	})
+	return operationResult, err
+}
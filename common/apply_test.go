@@ -0,0 +1,116 @@
+package common
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubeflow/spark-operator/api/v1beta2"
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// newApplyClient Helper func to build a fake client that records apply patches. The fake client does not implement
+// server-side apply, so applies are emulated with a create or an update.
+func newApplyClient(fieldManagers *[]string, objects ...ctrlClient.Object) ctrlClient.Client {
+	return fake.NewClientBuilder().WithObjects(objects...).WithInterceptorFuncs(interceptor.Funcs{
+		Patch: func(ctx context.Context, c ctrlClient.WithWatch, obj ctrlClient.Object, patch ctrlClient.Patch, opts ...ctrlClient.PatchOption) error {
+			if patch.Type() != types.ApplyPatchType {
+				return c.Patch(ctx, obj, patch, opts...)
+			}
+			patchOptions := &ctrlClient.PatchOptions{}
+			patchOptions.ApplyOptions(opts)
+			*fieldManagers = append(*fieldManagers, patchOptions.FieldManager)
+
+			existing := obj.DeepCopyObject().(ctrlClient.Object)
+			if err := c.Get(ctx, ctrlClient.ObjectKeyFromObject(obj), existing); apiErrors.IsNotFound(err) {
+				return c.Create(ctx, obj)
+			}
+			obj.SetResourceVersion(existing.GetResourceVersion())
+			return c.Update(ctx, obj)
+		},
+	}).Build()
+}
+
+func TestServerSideApply(t *testing.T) {
+	app := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "test-app", UID: "test-app-uid"}}
+	otherApp := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "other-app", UID: "other-app-uid"}}
+	newConfigMap := func(owner *v1beta2.SparkApplication) *apiv1.ConfigMap {
+		return &apiv1.ConfigMap{
+			TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "test-app-driver-conf-map",
+				Namespace:       "default",
+				OwnerReferences: []metav1.OwnerReference{*GetOwnerReference(owner)},
+			},
+			Data: map[string]string{"spark.properties": "spark.app.name=test-app"},
+		}
+	}
+
+	t.Run("creates and updates", func(t *testing.T) {
+		var fieldManagers []string
+		cl := newApplyClient(&fieldManagers)
+		operationResult, err := ServerSideApply(context.TODO(), cl, newConfigMap(app), "configmaps", SubmitOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, controllerutil.OperationResultCreated, operationResult)
+
+		operationResult, err = ServerSideApply(context.TODO(), cl, newConfigMap(app), "configmaps", SubmitOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, controllerutil.OperationResultUpdated, operationResult)
+		assert.Equal(t, []string{FieldManager, FieldManager}, fieldManagers)
+	})
+
+	t.Run("refuses an object of another application", func(t *testing.T) {
+		var fieldManagers []string
+		cl := newApplyClient(&fieldManagers, newConfigMap(otherApp))
+		_, err := ServerSideApply(context.TODO(), cl, newConfigMap(app), "configmaps", SubmitOptions{})
+		assert.True(t, apiErrors.IsConflict(err), "expected a conflict, got %v", err)
+		assert.Empty(t, fieldManagers)
+
+		operationResult, err := ServerSideApply(context.TODO(), cl, newConfigMap(app), "configmaps", SubmitOptions{AllowOwnershipTakeover: true})
+		assert.NoError(t, err)
+		assert.Equal(t, controllerutil.OperationResultUpdated, operationResult)
+	})
+}
+
+func TestCreateOrUpdate(t *testing.T) {
+	app := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "test-app", UID: "test-app-uid"}}
+	otherApp := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "other-app", UID: "other-app-uid"}}
+	newConfigMap := func(owner *v1beta2.SparkApplication, value string) *apiv1.ConfigMap {
+		return &apiv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "test-app-profiler",
+				Namespace:       "default",
+				OwnerReferences: []metav1.OwnerReference{*GetOwnerReference(owner)},
+			},
+			Data: map[string]string{"agent.conf": value},
+		}
+	}
+
+	cl := fake.NewClientBuilder().Build()
+	operationResult, err := CreateOrUpdate(context.TODO(), cl, newConfigMap(app, "a"), "configmaps", SubmitOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, controllerutil.OperationResultCreated, operationResult)
+
+	operationResult, err = CreateOrUpdate(context.TODO(), cl, newConfigMap(app, "b"), "configmaps", SubmitOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, controllerutil.OperationResultUpdated, operationResult)
+	stored := &apiv1.ConfigMap{}
+	assert.NoError(t, cl.Get(context.TODO(), ctrlClient.ObjectKey{Namespace: "default", Name: "test-app-profiler"}, stored))
+	assert.Equal(t, "b", stored.Data["agent.conf"])
+
+	_, err = CreateOrUpdate(context.TODO(), cl, newConfigMap(otherApp, "c"), "configmaps", SubmitOptions{})
+	assert.True(t, apiErrors.IsConflict(err), "expected a conflict, got %v", err)
+
+	var fieldManagers []string
+	operationResult, err = CreateOrUpdate(context.TODO(), newApplyClient(&fieldManagers), newConfigMap(app, "a"), "configmaps", SubmitOptions{ApplyMode: ApplyModeServerSide})
+	assert.NoError(t, err)
+	assert.Equal(t, controllerutil.OperationResultCreated, operationResult)
+	assert.Equal(t, []string{FieldManager}, fieldManagers)
+}
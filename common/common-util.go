@@ -48,7 +48,7 @@ func GetDriverPodName(app *v1beta2.SparkApplication) string {
 	}
 	return fmt.Sprintf("%s-driver", app.Name)
 }
-func GetDriverPort(sparkConfKeyValuePairs map[string]string) int {
+func GetDriverPort(sparkConfKeyValuePairs map[string]string) (int, error) {
 	//Checking if port information is passed in the spec, and using same
 	// or using the default ones
 	driverPortToBeUsed := DefaultDriverPort
@@ -56,12 +56,12 @@ func GetDriverPort(sparkConfKeyValuePairs map[string]string) int {
 	if valueExists {
 		driverPortSupplied, err := strconv.Atoi(driverPort)
 		if err != nil {
-			panic("Driver Port not parseable - hence failing the spark submit" + fmt.Sprint(err))
+			return 0, fmt.Errorf("driver port %q not parseable - hence failing the spark submit: %w", driverPort, err)
 		} else {
 			driverPortToBeUsed = driverPortSupplied
 		}
 	}
-	return driverPortToBeUsed
+	return driverPortToBeUsed, nil
 }
 
 // Helper func to get Owner references to be added to Spark Application resources - pod, service, configmap
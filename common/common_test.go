@@ -204,7 +204,8 @@ func TestGetDriverPort(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := GetDriverPort(tt.conf)
+			result, err := GetDriverPort(tt.conf)
+			assert.NoError(t, err)
 			assert.Equal(t, tt.expected, result)
 		})
 	}
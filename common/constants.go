@@ -1,10 +1,12 @@
 package common
 
 const (
-	DefaultUiPort                     = 4040
-	DefaultDriverPort                 = 7078
-	DefaultBlockManagerPort           = 7079
-	SparkDriverPort                   = "spark.driver.port"
+	DefaultUiPort           = 4040
+	DefaultDriverPort       = 7078
+	DefaultBlockManagerPort = 7079
+	SparkDriverPort         = "spark.driver.port"
+	// RedactedValue replaces secret values that are shown rather than submitted
+	RedactedValue                     = "<present_but_redacted>"
 	JavaScalaMemoryOverheadFactor     = "0.10"
 	OtherLanguageMemoryOverheadFactor = "0.40"
 	// SparkAppNamespaceKey is the configuration property for application namespace.
@@ -29,4 +31,8 @@ const (
 	SparkDriverCoreLimitKey = "spark.kubernetes.driver.limit.cores"
 	// SparkDriverCoreRequestKey is the configuration property for specifying the physical CPU request for the driver.
 	SparkDriverCoreRequestKey = "spark.kubernetes.driver.request.cores"
+	// SparkDriverSchedulerNameKey and SparkSchedulerNameKey are the configuration properties for the scheduler of
+	// the driver pod and of all pods of the application
+	SparkDriverSchedulerNameKey = "spark.kubernetes.driver.scheduler.name"
+	SparkSchedulerNameKey       = "spark.kubernetes.scheduler.name"
 )
@@ -0,0 +1,13 @@
+package common
+
+// SubmitOptions holds the settings that control how the rendered resources are written to the API server
+type SubmitOptions struct {
+	// DriverPodDeletionGracePeriodSeconds is the grace period used when deleting the driver pod of a previous
+	// submission. Nil uses the termination grace period of the pod itself.
+	DriverPodDeletionGracePeriodSeconds *int64
+	// AllowOwnershipTakeover lets existing resources owned by a different SparkApplication be adopted and
+	// overwritten instead of failing with a conflict
+	AllowOwnershipTakeover bool
+	// ApplyMode selects between the Get, Create or Update loop and server-side apply. Empty means ApplyModeUpdate.
+	ApplyMode ApplyMode
+}
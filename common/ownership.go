@@ -0,0 +1,106 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/kubeflow/spark-operator/api/v1beta2"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ownershipConflict marks a conflict raised by CheckOwnership; unlike an update conflict, retrying cannot resolve it
+type ownershipConflict struct {
+	*apiErrors.StatusError
+}
+
+// IsRetriableConflict reports whether err is an update conflict worth retrying, as opposed to an ownership conflict
+func IsRetriableConflict(err error) bool {
+	var ownershipErr ownershipConflict
+	return apiErrors.IsConflict(err) && !errors.As(err, &ownershipErr)
+}
+
+// CheckOwnership returns a conflict error when the existing object is owned by a SparkApplication other than the
+// owner of the desired object, so two applications resolving to the same names cannot overwrite each other's
+// resources. Objects without a SparkApplication owner are not refused. allowTakeover disables the check.
+func CheckOwnership(existing metav1.Object, desired metav1.Object, resource string, allowTakeover bool) error {
+	if allowTakeover {
+		return nil
+	}
+	desiredOwner := getSparkApplicationOwner(desired.GetOwnerReferences())
+	if desiredOwner == nil {
+		return nil
+	}
+	for _, ownerReference := range existing.GetOwnerReferences() {
+		if isSparkApplicationOwner(ownerReference) && ownerReference.UID != desiredOwner.UID {
+			return ownershipConflict{apiErrors.NewConflict(schema.GroupResource{Resource: resource}, existing.GetName(),
+				fmt.Errorf("it is owned by SparkApplication %s (uid %s), not by SparkApplication %s (uid %s); enable ownership takeover to adopt it",
+					ownerReference.Name, ownerReference.UID, desiredOwner.Name, desiredOwner.UID))}
+		}
+	}
+	return nil
+}
+
+// AdoptExisting is the ownership transfer every resource kind goes through before an existing object is overwritten.
+// It refuses the object with CheckOwnership, then merges the labels, annotations and SparkApplication owner of the
+// desired object into it. Foreign labels, annotations, owner references, finalizers and the resource version are kept.
+func AdoptExisting(existing metav1.Object, desired metav1.Object, resource string, allowTakeover bool) error {
+	if err := CheckOwnership(existing, desired, resource, allowTakeover); err != nil {
+		return err
+	}
+	SetSparkApplicationOwner(existing, desired)
+	existing.SetLabels(mergeStringMaps(existing.GetLabels(), desired.GetLabels()))
+	existing.SetAnnotations(mergeStringMaps(existing.GetAnnotations(), desired.GetAnnotations()))
+	return nil
+}
+
+// SetSparkApplicationOwner replaces the SparkApplication owner references of the existing object with the one of
+// the desired object, keeping owner references of any other kind
+func SetSparkApplicationOwner(existing metav1.Object, desired metav1.Object) {
+	desiredOwner := getSparkApplicationOwner(desired.GetOwnerReferences())
+	if desiredOwner == nil {
+		return
+	}
+	ownerReferences := []metav1.OwnerReference{*desiredOwner}
+	for _, ownerReference := range existing.GetOwnerReferences() {
+		if !isSparkApplicationOwner(ownerReference) {
+			ownerReferences = append(ownerReferences, ownerReference)
+		}
+	}
+	existing.SetOwnerReferences(ownerReferences)
+}
+
+// Helper func to find the SparkApplication among the owner references of an object
+func getSparkApplicationOwner(ownerReferences []metav1.OwnerReference) *metav1.OwnerReference {
+	for index := range ownerReferences {
+		if isSparkApplicationOwner(ownerReferences[index]) {
+			return &ownerReferences[index]
+		}
+	}
+	return nil
+}
+
+// Helper func to check whether an owner reference points at a SparkApplication
+func isSparkApplicationOwner(ownerReference metav1.OwnerReference) bool {
+	groupVersion, err := schema.ParseGroupVersion(ownerReference.APIVersion)
+	if err != nil {
+		return false
+	}
+	return groupVersion.Group == v1beta2.SchemeGroupVersion.Group && ownerReference.Kind == reflect.TypeOf(v1beta2.SparkApplication{}).Name()
+}
+
+// Helper func to copy the entries of generated over the ones of existing
+func mergeStringMaps(existing map[string]string, generated map[string]string) map[string]string {
+	if len(generated) == 0 {
+		return existing
+	}
+	if existing == nil {
+		existing = make(map[string]string, len(generated))
+	}
+	for key, val := range generated {
+		existing[key] = val
+	}
+	return existing
+}
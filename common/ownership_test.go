@@ -0,0 +1,99 @@
+package common
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kubeflow/spark-operator/api/v1beta2"
+	"github.com/stretchr/testify/assert"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func ownedBy(uid types.UID) *metav1.ObjectMeta {
+	app := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "app-" + string(uid), UID: uid}}
+	return &metav1.ObjectMeta{Name: "test-app-driver", OwnerReferences: []metav1.OwnerReference{*GetOwnerReference(app)}}
+}
+
+func TestCheckOwnership(t *testing.T) {
+	tests := []struct {
+		name          string
+		existing      *metav1.ObjectMeta
+		allowTakeover bool
+		wantConflict  bool
+	}{
+		{
+			name:     "owned by the same application",
+			existing: ownedBy("mine"),
+		},
+		{
+			name:     "not owned by any application",
+			existing: &metav1.ObjectMeta{Name: "test-app-driver"},
+		},
+		{
+			name: "owned by an object of another kind",
+			existing: &metav1.ObjectMeta{Name: "test-app-driver", OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "other", UID: "other"},
+			}},
+		},
+		{
+			name:         "owned by another application",
+			existing:     ownedBy("other"),
+			wantConflict: true,
+		},
+		{
+			name:          "owned by another application with takeover allowed",
+			existing:      ownedBy("other"),
+			allowTakeover: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckOwnership(tt.existing, ownedBy("mine"), "pods", tt.allowTakeover)
+			if tt.wantConflict {
+				assert.True(t, apiErrors.IsConflict(err), "expected a conflict, got %v", err)
+				assert.Contains(t, err.Error(), "app-other")
+				assert.False(t, IsRetriableConflict(err))
+				assert.False(t, IsRetriableConflict(fmt.Errorf("wrapped: %w", err)))
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestIsRetriableConflict(t *testing.T) {
+	assert.True(t, IsRetriableConflict(apiErrors.NewConflict(schema.GroupResource{Resource: "pods"}, "test", fmt.Errorf("stale"))))
+	assert.False(t, IsRetriableConflict(fmt.Errorf("not an api error")))
+}
+
+func TestSetSparkApplicationOwner(t *testing.T) {
+	existing := ownedBy("other")
+	existing.OwnerReferences = append(existing.OwnerReferences, metav1.OwnerReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "rs", UID: "rs"})
+
+	SetSparkApplicationOwner(existing, ownedBy("mine"))
+	assert.Len(t, existing.OwnerReferences, 2)
+	assert.Equal(t, types.UID("mine"), existing.OwnerReferences[0].UID)
+	assert.Equal(t, "ReplicaSet", existing.OwnerReferences[1].Kind)
+}
+
+func TestAdoptExisting(t *testing.T) {
+	existing := ownedBy("other")
+	existing.Labels = map[string]string{"team": "data", "app": "other"}
+	existing.Finalizers = []string{"example.com/finalizer"}
+	desired := ownedBy("mine")
+	desired.Labels = map[string]string{"app": "mine"}
+	desired.Annotations = map[string]string{"note": "generated"}
+
+	err := AdoptExisting(existing, desired, "pods", false)
+	assert.True(t, apiErrors.IsConflict(err), "expected a conflict, got %v", err)
+
+	assert.NoError(t, AdoptExisting(existing, desired, "pods", true))
+	assert.Equal(t, desired.OwnerReferences, existing.OwnerReferences)
+	assert.Equal(t, map[string]string{"team": "data", "app": "mine"}, existing.Labels)
+	assert.Equal(t, map[string]string{"note": "generated"}, existing.Annotations)
+	assert.Equal(t, []string{"example.com/finalizer"}, existing.Finalizers)
+}
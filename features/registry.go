@@ -0,0 +1,43 @@
+package features
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Registry holds the custom feature steps, which run in registration order after the built-in steps of the driver pod
+type Registry struct {
+	mu    sync.RWMutex
+	steps []Step
+}
+
+// DefaultRegistry is the registry used by native submit unless it is given one of its own
+var DefaultRegistry = &Registry{}
+
+// Register appends step to the default registry
+func Register(step Step) error {
+	return DefaultRegistry.Register(step)
+}
+
+// Register appends step to the registry. Step names must be unique within a registry.
+func (r *Registry) Register(step Step) error {
+	if step == nil {
+		return fmt.Errorf("feature step cannot be nil")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, registered := range r.steps {
+		if registered.Name() == step.Name() {
+			return fmt.Errorf("feature step %s is already registered", step.Name())
+		}
+	}
+	r.steps = append(r.steps, step)
+	return nil
+}
+
+// Steps returns the registered steps in registration order
+func (r *Registry) Steps() []Step {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Step(nil), r.steps...)
+}
@@ -0,0 +1,94 @@
+package features
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kubeflow/spark-operator/api/v1beta2"
+	apiv1 "k8s.io/api/core/v1"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DriverConf holds the inputs every feature step of the driver pod can rely on, like Spark's KubernetesDriverConf
+type DriverConf struct {
+	App *v1beta2.SparkApplication
+	// ConfigMapName is the name of the Spark Application ConfigMap mounted by the driver pod
+	ConfigMapName string
+	// Labels are the driver pod labels, which the driver service selects the pod by
+	Labels map[string]string
+	// AppSpecVolumeMounts and AppSpecVolumes are the driver volume mounts and volumes of the Spark Application, as
+	// they were before the local dir volumes were filtered out of the app spec
+	AppSpecVolumeMounts []apiv1.VolumeMount
+	AppSpecVolumes      []apiv1.Volume
+	// DryRun is set when the resources are rendered to be shown or compared rather than submitted. Steps must then
+	// not read secrets from the submission host, and put common.RedactedValue in the Secrets they contribute instead.
+	DryRun bool
+}
+
+// SparkPod is the driver pod under construction. Container is the Spark driver container, which is kept apart from
+// Pod.Spec.Containers until the pipeline has run and then becomes the first container of the pod.
+type SparkPod struct {
+	Pod       *apiv1.Pod
+	Container *apiv1.Container
+}
+
+// Step is one feature of the driver pod, modelled on Spark's KubernetesFeatureConfigStep. Steps run in order, each
+// one sees the pod as configured by the steps before it. Embed NoopStep to implement only the methods a step needs.
+type Step interface {
+	// Name identifies the step in errors and in the registry
+	Name() string
+	// ConfigurePod applies the feature to the driver pod and its Spark container
+	ConfigurePod(ctx context.Context, conf *DriverConf, pod *SparkPod) error
+	// SparkProperties returns properties to add to the spark.properties file read by the driver. They take
+	// precedence over the generated properties.
+	SparkProperties(ctx context.Context, conf *DriverConf) (map[string]string, error)
+	// AdditionalResources returns Kubernetes objects to create along with the driver pod, before it. Objects
+	// without a namespace or owner reference get the ones of the Spark Application.
+	AdditionalResources(ctx context.Context, conf *DriverConf) ([]ctrlClient.Object, error)
+}
+
+// NoopStep implements every method of Step except Name as a no-op
+type NoopStep struct{}
+
+func (NoopStep) ConfigurePod(ctx context.Context, conf *DriverConf, pod *SparkPod) error {
+	return nil
+}
+
+func (NoopStep) SparkProperties(ctx context.Context, conf *DriverConf) (map[string]string, error) {
+	return nil, nil
+}
+
+func (NoopStep) AdditionalResources(ctx context.Context, conf *DriverConf) ([]ctrlClient.Object, error) {
+	return nil, nil
+}
+
+// Contributions collects what the steps contributed besides the changes to the driver pod
+type Contributions struct {
+	// SparkProperties holds the properties of all steps, a later step overriding the properties of an earlier one
+	SparkProperties map[string]string
+	// AdditionalResources holds the objects of all steps, in step order
+	AdditionalResources []ctrlClient.Object
+}
+
+// Run runs the steps in order on pod, and collects their Spark properties and additional resources
+func Run(ctx context.Context, conf *DriverConf, pod *SparkPod, steps []Step) (*Contributions, error) {
+	contributions := &Contributions{SparkProperties: make(map[string]string)}
+	for _, step := range steps {
+		if err := step.ConfigurePod(ctx, conf, pod); err != nil {
+			return nil, fmt.Errorf("feature step %s failed to configure the driver pod: %w", step.Name(), err)
+		}
+		properties, err := step.SparkProperties(ctx, conf)
+		if err != nil {
+			return nil, fmt.Errorf("feature step %s failed to provide spark properties: %w", step.Name(), err)
+		}
+		for key, value := range properties {
+			contributions.SparkProperties[key] = value
+		}
+		resources, err := step.AdditionalResources(ctx, conf)
+		if err != nil {
+			return nil, fmt.Errorf("feature step %s failed to provide additional resources: %w", step.Name(), err)
+		}
+		contributions.AdditionalResources = append(contributions.AdditionalResources, resources...)
+	}
+	return contributions, nil
+}
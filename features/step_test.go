@@ -0,0 +1,82 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/kubeflow/spark-operator/api/v1beta2"
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// testStep labels the pod with its name and contributes a property and a ConfigMap named after it
+type testStep struct {
+	NoopStep
+	name string
+	err  error
+}
+
+func (s testStep) Name() string {
+	return s.name
+}
+
+func (s testStep) ConfigurePod(ctx context.Context, conf *DriverConf, pod *SparkPod) error {
+	if s.err != nil {
+		return s.err
+	}
+	if pod.Pod.Labels == nil {
+		pod.Pod.Labels = make(map[string]string)
+	}
+	pod.Pod.Labels["step"] = s.name
+	return nil
+}
+
+func (s testStep) SparkProperties(ctx context.Context, conf *DriverConf) (map[string]string, error) {
+	return map[string]string{"spark.test.step": s.name, "spark.test." + s.name: "true"}, nil
+}
+
+func (s testStep) AdditionalResources(ctx context.Context, conf *DriverConf) ([]ctrlClient.Object, error) {
+	return []ctrlClient.Object{&apiv1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: s.name}}}, nil
+}
+
+func TestRun(t *testing.T) {
+	conf := &DriverConf{App: &v1beta2.SparkApplication{}}
+
+	t.Run("runs the steps in order", func(t *testing.T) {
+		pod := &SparkPod{Pod: &apiv1.Pod{}, Container: &apiv1.Container{}}
+		contributions, err := Run(context.TODO(), conf, pod, []Step{testStep{name: "first"}, testStep{name: "second"}})
+		assert.NoError(t, err)
+		assert.Equal(t, "second", pod.Pod.Labels["step"])
+		assert.Equal(t, map[string]string{"spark.test.step": "second", "spark.test.first": "true", "spark.test.second": "true"}, contributions.SparkProperties)
+		assert.Len(t, contributions.AdditionalResources, 2)
+		assert.Equal(t, "first", contributions.AdditionalResources[0].GetName())
+		assert.Equal(t, "second", contributions.AdditionalResources[1].GetName())
+	})
+
+	t.Run("stops at a failing step", func(t *testing.T) {
+		pod := &SparkPod{Pod: &apiv1.Pod{}, Container: &apiv1.Container{}}
+		_, err := Run(context.TODO(), conf, pod, []Step{testStep{name: "broken", err: fmt.Errorf("boom")}, testStep{name: "second"}})
+		assert.EqualError(t, err, "feature step broken failed to configure the driver pod: boom")
+		assert.Empty(t, pod.Pod.Labels)
+	})
+}
+
+func TestRegistry(t *testing.T) {
+	registry := &Registry{}
+	assert.NoError(t, registry.Register(testStep{name: "first"}))
+	assert.NoError(t, registry.Register(testStep{name: "second"}))
+	assert.EqualError(t, registry.Register(testStep{name: "first"}), "feature step first is already registered")
+	assert.Error(t, registry.Register(nil))
+
+	steps := registry.Steps()
+	assert.Len(t, steps, 2)
+	assert.Equal(t, "first", steps[0].Name())
+	assert.Equal(t, "second", steps[1].Name())
+
+	// The returned slice is a copy
+	steps[0] = testStep{name: "replaced"}
+	assert.Equal(t, "first", registry.Steps()[0].Name())
+}
@@ -39,6 +39,8 @@ const (
 	SparkPropertiesFileName        = "spark.properties"
 	HadoopConfDir                  = "HADOOP_CONF_DIR"
 	HadoopConfDirPath              = "/opt/hadoop/conf"
+	SparkHadoopConfigMapNameKey    = "spark.kubernetes.hadoop.configMapName"
+	OAuthTokenConfSuffix           = ".oauthToken"
 	SparkEnvScriptFileName         = "spark-env.sh"
 	SparkEnvScriptFileCommand      = "export SPARK_LOCAL_IP=$(hostname -i)\n"
 	SparkSubmitDeploymentMode      = "spark.submit.deployMode"
@@ -1,25 +1,42 @@
 package configmap
 
 import (
+	"context"
 	"fmt"
 	"nativesubmit/common"
+	"nativesubmit/internal/executor"
+	"nativesubmit/internal/tracing"
 	"path"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/kubeflow/spark-operator/api/v1beta2"
 	"github.com/magiconair/properties"
+	"go.opentelemetry.io/otel/attribute"
+	apiv1 "k8s.io/api/core/v1"
 	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 // Function to create Spark Application Configmap
 // Spark Application ConfigMap is pre-requisite for Driver Pod Creation; this configmap is mounted on driver pod
 // Spark Application ConfigMap acts as configuration repository for the Driver, executor pods
-func Create(app *v1beta2.SparkApplication, submissionID string, createdApplicationId string, kubeClient ctrlClient.Client, driverConfigMapName string, serviceName string) error {
+func Create(ctx context.Context, app *v1beta2.SparkApplication, submissionID string, createdApplicationId string, kubeClient ctrlClient.Client, driverConfigMapName string, serviceName string) error {
+	configMap, err := Build(app, submissionID, createdApplicationId, driverConfigMapName, serviceName)
+	if err != nil {
+		return err
+	}
+	_, err = CreateOrUpdate(tracing.WithApplication(ctx, app), configMap, kubeClient, common.SubmitOptions{})
+	return err
+}
+
+// Build renders the Spark Application ConfigMap without calling the API server
+func Build(app *v1beta2.SparkApplication, submissionID string, createdApplicationId string, driverConfigMapName string, serviceName string) (*apiv1.ConfigMap, error) {
 	if app == nil {
-		return fmt.Errorf("spark application cannot be nil")
+		return nil, fmt.Errorf("spark application cannot be nil")
 	}
 
 	var errorSubmissionCommandArgs error
@@ -34,14 +51,47 @@ func Create(app *v1beta2.SparkApplication, submissionID string, createdApplicati
 	// Utility function buildAltSubmissionCommandArgs to add other key, value configuration pairs
 	driverConfigMapData[SparkPropertiesFileName], errorSubmissionCommandArgs = buildAltSubmissionCommandArgs(app, common.GetDriverPodName(app), submissionID, createdApplicationId, serviceName)
 	if errorSubmissionCommandArgs != nil {
-		return fmt.Errorf("failed to create submission command args for the driver configmap %s in namespace %s: %v", driverConfigMapName, app.Namespace, errorSubmissionCommandArgs)
+		return nil, fmt.Errorf("failed to create submission command args for the driver configmap %s in namespace %s: %v", driverConfigMapName, app.Namespace, errorSubmissionCommandArgs)
+	}
+	configMap := buildConfigMap(driverConfigMapName, app, submissionID, createdApplicationId, driverConfigMapData)
+
+	// The executor pod template is mounted next to spark.properties, which points the driver at it
+	executorPodTemplate, err := executor.RenderPodTemplate(app)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render the executor pod template for the driver configmap %s in namespace %s: %w", driverConfigMapName, app.Namespace, err)
+	}
+	if executorPodTemplate != "" {
+		configMap.Data[executor.PodTemplateFileName] = executorPodTemplate
+		AppendSparkProperties(configMap, map[string]string{
+			executor.SparkExecutorPodTemplateFileKey:          executor.PodTemplateFilePath,
+			executor.SparkExecutorPodTemplateContainerNameKey: executor.PodTemplateContainerName,
+		})
+	}
+	return configMap, nil
+}
+
+// CreateOrUpdate submits a rendered Spark Application ConfigMap to the API server and reports whether it was created or updated
+// An existing configmap owned by a different Spark Application is only overwritten when ownership takeover is allowed.
+// With server-side apply, keys and metadata owned by other field managers are preserved.
+func CreateOrUpdate(ctx context.Context, configMap *apiv1.ConfigMap, kubeClient ctrlClient.Client, opts common.SubmitOptions) (operationResult controllerutil.OperationResult, err error) {
+	ctx, span := tracing.StartSpan(ctx, "configmap.CreateOrUpdate", attribute.String("k8s.configmap.name", configMap.Name))
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
+
+	if opts.ApplyMode == common.ApplyModeServerSide {
+		operationResult, applyErr := common.ServerSideApply(ctx, kubeClient, configMap, "configmaps", opts)
+		if applyErr != nil {
+			return operationResult, fmt.Errorf("failed to apply driver configmap %s in namespace %s: %w", configMap.Name, configMap.Namespace, applyErr)
+		}
+		return operationResult, nil
 	}
 	//Create Spark Application ConfigMap
-	createErr := createConfigMapUtil(driverConfigMapName, app, driverConfigMapData, kubeClient)
+	operationResult, createErr := createConfigMapUtil(ctx, configMap, kubeClient, opts)
 	if createErr != nil {
-		return fmt.Errorf("failed to create/update driver configmap %s in namespace %s: %v", driverConfigMapName, app.Namespace, createErr)
+		return operationResult, fmt.Errorf("failed to create/update driver configmap %s in namespace %s: %w", configMap.Name, configMap.Namespace, createErr)
 	}
-	return nil
+	return operationResult, nil
 }
 
 // Helper func to create key/value pairs required for the Spark Application Configmap
@@ -80,9 +130,9 @@ func buildAltSubmissionCommandArgs(app *v1beta2.SparkApplication, driverPodName
 	sb.WriteString(fmt.Sprintf("%s=%s", SparkDriverPodNameKey, driverPodName))
 	sb.WriteString(NewLineString)
 
-	sb.WriteString(populateArtifacts(sb.String(), *app))
+	sb.WriteString(populateArtifacts("", *app))
 
-	sb.WriteString(populateContainerImageDetails(sb.String(), *app))
+	sb.WriteString(populateContainerImageDetails("", *app))
 	if app.Spec.PythonVersion != nil {
 		sb.WriteString(fmt.Sprintf("%s=%s", SparkPythonVersion, *app.Spec.PythonVersion))
 		sb.WriteString(NewLineString)
@@ -100,7 +150,7 @@ func buildAltSubmissionCommandArgs(app *v1beta2.SparkApplication, driverPodName
 	sb.WriteString(fmt.Sprintf("%s=false", SparkWaitAppCompletion))
 	sb.WriteString(NewLineString)
 
-	sb.WriteString(populateSparkConfProperties(sb.String(), sparkConfKeyValuePairs))
+	sb.WriteString(populateSparkConfProperties("", sparkConfKeyValuePairs))
 
 	// Add Hadoop configuration properties.
 	for key, value := range app.Spec.HadoopConf {
@@ -112,6 +162,11 @@ func buildAltSubmissionCommandArgs(app *v1beta2.SparkApplication, driverPodName
 		sb.WriteString(fmt.Sprintf("spark.hadoop.%s=%s", HadoopConfDir, HadoopConfDirPath))
 		sb.WriteString(NewLineString)
 	}
+	if app.Spec.HadoopConfigMap != nil {
+		// Spark mounts the Hadoop ConfigMap in the executor pods
+		sb.WriteString(fmt.Sprintf("%s=%s", SparkHadoopConfigMapNameKey, *app.Spec.HadoopConfigMap))
+		sb.WriteString(NewLineString)
+	}
 
 	// Add the driver and executor configuration options.
 	// Note that when the controller submits the application, it expects that all dependencies are local
@@ -136,7 +191,7 @@ func buildAltSubmissionCommandArgs(app *v1beta2.SparkApplication, driverPodName
 	}
 	sb = *sbPtr
 
-	sb.WriteString(populateMemoryInfo(sb.String(), *app, sparkConfKeyValuePairs))
+	sb.WriteString(populateMemoryInfo("", *app, sparkConfKeyValuePairs))
 
 	if app.Spec.Driver.ServiceAccount != nil {
 		sb.WriteString(fmt.Sprintf("%s=%s", SparkDriverServiceAccountName, *app.Spec.Driver.ServiceAccount))
@@ -167,7 +222,7 @@ func buildAltSubmissionCommandArgs(app *v1beta2.SparkApplication, driverPodName
 		sb.WriteString(fmt.Sprintf("%s%s=%s", SparkDriverLabelKeyPrefix, key, value))
 		sb.WriteString(NewLineString)
 	}
-	sb.WriteString(populateDriverAnnotations(sb.String(), *app))
+	sb.WriteString(populateDriverAnnotations("", *app))
 
 	for key, value := range app.Spec.Driver.EnvSecretKeyRefs {
 		sb.WriteString(fmt.Sprintf("%s%s=%s:%s", SparkDriverSecretKeyRefKeyPrefix, key, value.Name, value.Key))
@@ -179,7 +234,7 @@ func buildAltSubmissionCommandArgs(app *v1beta2.SparkApplication, driverPodName
 		sb.WriteString(NewLineString)
 	}
 
-	sb.WriteString(populateDriverSecrets(sb.String(), *app))
+	sb.WriteString(populateDriverSecrets("", *app))
 
 	for key, value := range app.Spec.Driver.EnvVars {
 		sb.WriteString(fmt.Sprintf("%s%s=%s", SparkDriverEnvVarConfigKeyPrefix, key, value))
@@ -187,9 +242,12 @@ func buildAltSubmissionCommandArgs(app *v1beta2.SparkApplication, driverPodName
 
 	}
 
-	for key, value := range app.Spec.Driver.Env {
-		sb.WriteString(fmt.Sprintf("%s%d=%s", SparkDriverEnvVarConfigKeyPrefix, key, value))
-		sb.WriteString(NewLineString)
+	// The driver pod gets spec.driver.env verbatim, only the variables with a literal value can be written as properties
+	for _, envVar := range app.Spec.Driver.Env {
+		if envVar.ValueFrom == nil {
+			sb.WriteString(fmt.Sprintf("%s%s=%s", SparkDriverEnvVarConfigKeyPrefix, envVar.Name, envVar.Value))
+			sb.WriteString(NewLineString)
+		}
 	}
 
 	sb.WriteString(fmt.Sprintf("%s%s=%s", SparkExecutorLabelKeyPrefix, SparkAppNameLabel, app.Name))
@@ -234,7 +292,7 @@ func buildAltSubmissionCommandArgs(app *v1beta2.SparkApplication, driverPodName
 		sb.WriteString(NewLineString)
 	}
 
-	sb.WriteString(populateExecutorAnnotations(sb.String(), *app))
+	sb.WriteString(populateExecutorAnnotations("", *app))
 
 	for key, value := range app.Spec.Executor.EnvSecretKeyRefs {
 		sb.WriteString(fmt.Sprintf("%s%s=%s:%s", SparkExecutorSecretKeyRefKeyPrefix, key, value.Name, value.Key))
@@ -246,14 +304,14 @@ func buildAltSubmissionCommandArgs(app *v1beta2.SparkApplication, driverPodName
 		sb.WriteString(NewLineString)
 	}
 
-	sb.WriteString(populateExecutorSecrets(sb.String(), *app))
+	sb.WriteString(populateExecutorSecrets("", *app))
 
 	for key, value := range app.Spec.Executor.EnvVars {
 		sb.WriteString(fmt.Sprintf("%s%s=%s", SparkExecutorEnvVarConfigKeyPrefix, key, value))
 		sb.WriteString(NewLineString)
 	}
 
-	sb.WriteString(populateDynamicAllocation(sb.String(), *app))
+	sb.WriteString(populateDynamicAllocation("", *app))
 	for key, value := range app.Spec.NodeSelector {
 		sb.WriteString(fmt.Sprintf("%s%s=%s", SparkNodeSelectorKeyPrefix, key, value))
 		sb.WriteString(NewLineString)
@@ -273,9 +331,13 @@ func buildAltSubmissionCommandArgs(app *v1beta2.SparkApplication, driverPodName
 	sb.WriteString(fmt.Sprintf("%s=%v", SparkDriverBlockManagerPort, common.DefaultBlockManagerPort))
 	sb.WriteString(NewLineString)
 
-	sb.WriteString(fmt.Sprintf("%s=%v", common.SparkDriverPort, common.GetDriverPort(sparkConfKeyValuePairs)))
+	driverPort, err := common.GetDriverPort(sparkConfKeyValuePairs)
+	if err != nil {
+		return sb.String(), err
+	}
+	sb.WriteString(fmt.Sprintf("%s=%v", common.SparkDriverPort, driverPort))
 	sb.WriteString(NewLineString)
-	sb.WriteString(populateAppSpecType(sb.String(), *app))
+	sb.WriteString(populateAppSpecType("", *app))
 	sb.WriteString(NewLineString)
 	sb.WriteString(fmt.Sprintf("%s=%v", SparkApplicationSubmitTime, time.Now().UnixMilli()))
 	sb.WriteString(NewLineString)
@@ -288,9 +350,9 @@ func buildAltSubmissionCommandArgs(app *v1beta2.SparkApplication, driverPodName
 	sb.WriteString(fmt.Sprintf("%s=%s", SparkUIProxyRedirectURI, ForwardSlash))
 	sb.WriteString(NewLineString)
 
-	sb.WriteString(populateProperties(sb.String()))
+	sb.WriteString(populateProperties(""))
 
-	sb.WriteString(populateMonitoringInfo(sb.String(), *app))
+	sb.WriteString(populateMonitoringInfo("", *app))
 
 	// Volumes
 	if app.Spec.Volumes != nil {
@@ -338,6 +400,10 @@ func populateSparkConfProperties(args string, sparkConfKeyValuePairs map[string]
 			if key == SparkDriverExtraClassPath || key == SparkExecutorExtraClassPath {
 				value = AddEscapeCharacter(value)
 			}
+			// Like Spark, inline OAuth tokens are shipped in the Kubernetes credentials Secret only
+			if strings.HasSuffix(key, OAuthTokenConfSuffix) {
+				value = common.RedactedValue
+			}
 			args = args + fmt.Sprintf("%s=%s", key, value) + NewLineString
 		}
 	}
@@ -585,3 +651,59 @@ func populateMemoryInfo(args string, app v1beta2.SparkApplication, sparkConfKeyV
 
 	return args
 }
+
+// AppendSparkProperties appends properties to the spark.properties file of a rendered ConfigMap in key order. Java
+// properties keep the last value of a repeated key, so the appended values win over the generated ones.
+func AppendSparkProperties(configMap *apiv1.ConfigMap, properties map[string]string) {
+	if len(properties) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	sb.WriteString(configMap.Data[SparkPropertiesFileName])
+	for _, key := range keys {
+		sb.WriteString(fmt.Sprintf("%s=%s", key, properties[key]))
+		sb.WriteString(NewLineString)
+	}
+	configMap.Data[SparkPropertiesFileName] = sb.String()
+}
+
+// MergeSparkConfigMap adds the properties of the spark-defaults.conf file of the application's Spark ConfigMap to the
+// spark.properties file of a rendered ConfigMap. The driver is started with --properties-file, so Spark would not read
+// that spark-defaults.conf itself; properties already generated for the application are kept.
+func MergeSparkConfigMap(ctx context.Context, kubeClient ctrlClient.Client, app *v1beta2.SparkApplication, configMap *apiv1.ConfigMap) error {
+	if app.Spec.SparkConfigMap == nil {
+		return nil
+	}
+	sparkConfigMap := &apiv1.ConfigMap{}
+	if err := kubeClient.Get(ctx, ctrlClient.ObjectKey{Namespace: app.Namespace, Name: *app.Spec.SparkConfigMap}, sparkConfigMap); err != nil {
+		return fmt.Errorf("failed to get spark configmap %s in namespace %s: %w", *app.Spec.SparkConfigMap, app.Namespace, err)
+	}
+	sparkDefaults, exists := sparkConfigMap.Data[DefaultSparkConfFileName]
+	if !exists {
+		return nil
+	}
+
+	// Spark does not expand ${...} references in its properties files, and neither should the merge
+	loader := properties.Loader{Encoding: properties.UTF8, DisableExpansion: true}
+	userProperties, err := loader.LoadBytes([]byte(sparkDefaults))
+	if err != nil {
+		return fmt.Errorf("failed to parse %s of spark configmap %s in namespace %s: %w", DefaultSparkConfFileName, *app.Spec.SparkConfigMap, app.Namespace, err)
+	}
+	generatedProperties, err := loader.LoadBytes([]byte(configMap.Data[SparkPropertiesFileName]))
+	if err != nil {
+		return fmt.Errorf("failed to parse %s of configmap %s in namespace %s: %w", SparkPropertiesFileName, configMap.Name, configMap.Namespace, err)
+	}
+	merged := make(map[string]string)
+	for _, key := range userProperties.Keys() {
+		if _, generated := generatedProperties.Get(key); !generated {
+			merged[key] = userProperties.MustGet(key)
+		}
+	}
+	AppendSparkProperties(configMap, merged)
+	return nil
+}
@@ -1,13 +1,18 @@
 package configmap
 
 import (
+	"context"
+	"nativesubmit/common"
 	"strings"
 	"testing"
 
 	v1beta2 "github.com/kubeflow/spark-operator/api/v1beta2"
+	"github.com/magiconair/properties"
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
@@ -63,7 +68,7 @@ func TestCreate(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			client := fake.NewClientBuilder().WithScheme(scheme).Build()
-			err := Create(tt.app, tt.submissionID, tt.applicationID, client, tt.driverConfigMapName, tt.serviceName)
+			err := Create(context.TODO(), tt.app, tt.submissionID, tt.applicationID, client, tt.driverConfigMapName, tt.serviceName)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -197,3 +202,165 @@ func TestPopulateMemoryInfo(t *testing.T) {
 func int64ptr(i int64) *int64 {
 	return &i
 }
+
+func TestMergeSparkConfigMap(t *testing.T) {
+	tests := []struct {
+		name           string
+		sparkConfigMap *string
+		existing       []ctrlClient.Object
+		wantErr        bool
+		wantProperties map[string]string
+	}{
+		{
+			name:           "no spark configmap",
+			wantProperties: map[string]string{"spark.app.name": "test-spark-app"},
+		},
+		{
+			name:           "generated properties are kept",
+			sparkConfigMap: stringptr("spark-conf"),
+			existing: []ctrlClient.Object{&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "spark-conf", Namespace: "default"},
+				Data: map[string]string{
+					DefaultSparkConfFileName: "spark.app.name=other\nspark.sql.catalogImplementation hive\nspark.eventLog.dir=${HOME}/events\n",
+					"hive-site.xml":          "<configuration/>",
+				},
+			}},
+			wantProperties: map[string]string{
+				"spark.app.name":                  "test-spark-app",
+				"spark.sql.catalogImplementation": "hive",
+				"spark.eventLog.dir":              "${HOME}/events",
+			},
+		},
+		{
+			name:           "spark configmap without spark-defaults.conf",
+			sparkConfigMap: stringptr("spark-conf"),
+			existing: []ctrlClient.Object{&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "spark-conf", Namespace: "default"},
+				Data:       map[string]string{"log4j2.properties": "rootLogger.level = info"},
+			}},
+			wantProperties: map[string]string{"spark.app.name": "test-spark-app"},
+		},
+		{
+			name:           "missing spark configmap",
+			sparkConfigMap: stringptr("spark-conf"),
+			wantErr:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &v1beta2.SparkApplication{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-spark-app", Namespace: "default"},
+				Spec:       v1beta2.SparkApplicationSpec{SparkConfigMap: tt.sparkConfigMap},
+			}
+			configMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-configmap", Namespace: "default"},
+				Data:       map[string]string{SparkPropertiesFileName: "spark.app.name=test-spark-app\n"},
+			}
+			client := fake.NewClientBuilder().WithObjects(tt.existing...).Build()
+			err := MergeSparkConfigMap(context.TODO(), client, app, configMap)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			loader := properties.Loader{Encoding: properties.UTF8, DisableExpansion: true}
+			merged, err := loader.LoadBytes([]byte(configMap.Data[SparkPropertiesFileName]))
+			if assert.NoError(t, err) {
+				assert.Equal(t, len(tt.wantProperties), merged.Len())
+				for key, value := range tt.wantProperties {
+					got, _ := merged.Get(key)
+					assert.Equal(t, value, got, key)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildAltSubmissionCommandArgsHadoopConfigMap(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		Spec: v1beta2.SparkApplicationSpec{
+			Type:            v1beta2.SparkApplicationTypeScala,
+			Mode:            v1beta2.DeployModeCluster,
+			HadoopConfigMap: stringptr("hadoop-conf"),
+		},
+	}
+
+	result, err := buildAltSubmissionCommandArgs(app, "test-driver", "test-submission", "test-app", "test-service")
+	assert.NoError(t, err)
+	assert.Contains(t, strings.Split(result, NewLineString), SparkHadoopConfigMapNameKey+"=hadoop-conf")
+}
+
+func TestBuildAltSubmissionCommandArgsRedactsOAuthTokens(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		Spec: v1beta2.SparkApplicationSpec{
+			Type:      v1beta2.SparkApplicationTypeScala,
+			Mode:      v1beta2.DeployModeCluster,
+			SparkConf: map[string]string{"spark.kubernetes.authenticate.driver.oauthToken": "secret-token"},
+		},
+	}
+
+	result, err := buildAltSubmissionCommandArgs(app, "test-driver", "test-submission", "test-app", "test-service")
+	assert.NoError(t, err)
+	assert.NotContains(t, result, "secret-token")
+	assert.Contains(t, strings.Split(result, NewLineString), "spark.kubernetes.authenticate.driver.oauthToken="+common.RedactedValue)
+}
+
+func TestBuildAltSubmissionCommandArgsDriverEnv(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		Spec: v1beta2.SparkApplicationSpec{
+			Type: v1beta2.SparkApplicationTypeScala,
+			Mode: v1beta2.DeployModeCluster,
+			Driver: v1beta2.DriverSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					Env: []corev1.EnvVar{
+						{Name: "FOO", Value: "bar"},
+						{Name: "HOST_IP", ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "status.hostIP"}}},
+					},
+				},
+			},
+		},
+	}
+
+	result, err := buildAltSubmissionCommandArgs(app, "test-driver", "test-submission", "test-app", "test-service")
+	assert.NoError(t, err)
+	lines := strings.Split(result, NewLineString)
+	assert.Contains(t, lines, SparkDriverEnvVarConfigKeyPrefix+"FOO=bar")
+	assert.NotContains(t, result, SparkDriverEnvVarConfigKeyPrefix+"HOST_IP")
+	assert.NotContains(t, result, SparkDriverEnvVarConfigKeyPrefix+"0=")
+}
+
+func TestBuildAltSubmissionCommandArgsWritesEachPropertyOnce(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"},
+		Spec: v1beta2.SparkApplicationSpec{
+			Type:      v1beta2.SparkApplicationTypeScala,
+			Mode:      v1beta2.DeployModeCluster,
+			Image:     common.StringPointer("spark:3.5.0"),
+			Deps:      v1beta2.Dependencies{Jars: []string{"local:///opt/spark/jars/app.jar"}},
+			SparkConf: map[string]string{"spark.test.key": "value"},
+			Driver: v1beta2.DriverSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{Annotations: map[string]string{"team": "data"}},
+			},
+			Executor:          v1beta2.ExecutorSpec{SparkPodSpec: v1beta2.SparkPodSpec{Annotations: map[string]string{"team": "data"}}},
+			DynamicAllocation: &v1beta2.DynamicAllocation{Enabled: true},
+			Monitoring:        &v1beta2.MonitoringSpec{},
+		},
+	}
+
+	result, err := buildAltSubmissionCommandArgs(app, "test-driver", "test-submission", "test-app", "test-service")
+	assert.NoError(t, err)
+	lines := map[string]int{}
+	for _, line := range strings.Split(result, NewLineString) {
+		if line != "" {
+			lines[line]++
+		}
+	}
+	for line, count := range lines {
+		assert.Equal(t, 1, count, "line %q is written more than once", line)
+	}
+	assert.Contains(t, lines, "spark.test.key=value")
+	assert.Contains(t, lines, "spark.kubernetes.driver.annotation.team=data")
+	assert.Contains(t, lines, "spark.dynamicAllocation.enabled=true")
+}
@@ -13,6 +13,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/util/retry"
 	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 const (
@@ -20,35 +21,59 @@ const (
 	kubernetesServicePortEnvVar = "KUBERNETES_SERVICE_PORT"
 )
 
-// CreateConfigMapUtil Helper func to create Spark Application configmap
-func createConfigMapUtil(configMapName string, app *v1beta2.SparkApplication, configMapData map[string]string, kubeClient ctrlClient.Client) error {
-	configMap := &apiv1.ConfigMap{
+// buildConfigMap Helper func to populate Spark Application configmap schema
+// The submission ID and application ID labels let a retried submission recognise the configmap it created earlier
+func buildConfigMap(configMapName string, app *v1beta2.SparkApplication, submissionID string, createdApplicationId string, configMapData map[string]string) *apiv1.ConfigMap {
+	return &apiv1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: apiv1.SchemeGroupVersion.String(),
+			Kind:       "ConfigMap",
+		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:            configMapName,
-			Namespace:       app.Namespace,
+			Name:      configMapName,
+			Namespace: app.Namespace,
+			Labels: map[string]string{
+				SparkAppNameLabel:             app.Name,
+				SparkApplicationSelectorLabel: createdApplicationId,
+				SubmissionIDLabel:             submissionID,
+			},
 			OwnerReferences: []metav1.OwnerReference{*common.GetOwnerReference(app)},
 		},
 		Data: configMapData,
 	}
+}
 
-	createConfigMapErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+// CreateConfigMapUtil Helper func to create Spark Application configmap
+func createConfigMapUtil(ctx context.Context, configMap *apiv1.ConfigMap, kubeClient ctrlClient.Client, opts common.SubmitOptions) (controllerutil.OperationResult, error) {
+	configMapData := configMap.Data
+	operationResult := controllerutil.OperationResultNone
+	createConfigMapErr := retry.OnError(retry.DefaultRetry, common.IsRetriableConflict, func() error {
 		existingConfigMap := &apiv1.ConfigMap{}
-		err := kubeClient.Get(context.TODO(), ctrlClient.ObjectKeyFromObject(configMap), existingConfigMap)
+		err := kubeClient.Get(ctx, ctrlClient.ObjectKeyFromObject(configMap), existingConfigMap)
 		//cm, err := kubeClient.CoreV1().ConfigMaps(app.Namespace).Get(context.TODO(), configMapName, metav1.GetOptions{})
 		if apiErrors.IsNotFound(err) {
-			createErr := kubeClient.Create(context.TODO(), configMap)
+			createErr := kubeClient.Create(ctx, configMap)
 			//_, createErr := kubeClient.CoreV1().ConfigMaps(app.Namespace).Create(context.TODO(), configMap, metav1.CreateOptions{})
+			if createErr == nil {
+				operationResult = controllerutil.OperationResultCreated
+			}
 			return createErr
 		}
 		if err != nil {
 			return err
 		}
+		if err := common.AdoptExisting(existingConfigMap, configMap, "configmaps", opts.AllowOwnershipTakeover); err != nil {
+			return err
+		}
 		existingConfigMap.Data = configMapData
-		updateErr := kubeClient.Update(context.TODO(), existingConfigMap)
+		updateErr := kubeClient.Update(ctx, existingConfigMap)
 		//_, updateErr := kubeClient.CoreV1().ConfigMaps(app.Namespace).Update(context.TODO(), cm, metav1.UpdateOptions{})
+		if updateErr == nil {
+			operationResult = controllerutil.OperationResultUpdated
+		}
 		return updateErr
 	})
-	return createConfigMapErr
+	return operationResult, createConfigMapErr
 }
 func AddEscapeCharacter(configMapArg string) string {
 	configMapArg = strings.ReplaceAll(configMapArg, ":", "\\:")
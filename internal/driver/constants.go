@@ -1,5 +1,7 @@
 package driver
 
+import "time"
+
 const (
 	SparkDriverCores          = "spark.driver.cores"
 	SparkDriverMemory         = "spark.driver.memory"
@@ -9,21 +11,37 @@ const (
 	KerberosFileVolume        = "krb5-file"
 	KerberosFileDirectoryPath = "/etc"
 	KerberosFileName          = "krb5.conf"
+	// KerberosFileConfigMapExtension names the ConfigMap created from the local krb5.conf of krb5.path
+	KerberosFileConfigMapExtension = "-krb5-file"
+	KerberosTokenSecretName        = "spark.kubernetes.kerberos.tokenSecret.name"
+	KerberosTokenSecretVolume      = "hadoop-secret"
+	KerberosKeytab                 = "spark.kerberos.keytab"
+	KerberosPrincipal              = "spark.kerberos.principal"
+	KerberosKeytabVolume           = "kerberos-keytab"
+	KerberosKeytabMountPath        = "/mnt/secrets/kerberos-keytab"
+	// KerberosKeytabSecretExtension names the Secret created from a local keytab
+	KerberosKeytabSecretExtension = "-kerberos-keytab"
+	LocalScheme                   = "local"
 
-	SparkDriverEnvPrefix             = "spark.kubernetes.driverEnv"
-	All                              = "ALL"
-	SparkUserId                      = "185"
-	SparkBlockManagerPort            = "spark.blockManager.port"
-	SparkDriverBlockManagerPort      = "spark.driver.blockManager.port"
-	DotSeparator                     = "."
-	SparkDriverDNSPolicy             = "ClusterFirst"
+	SparkDriverEnvPrefix        = "spark.kubernetes.driverEnv"
+	SecretKeyRefSeparator       = ":"
+	All                         = "ALL"
+	SparkUserId                 = "185"
+	SparkBlockManagerPort       = "spark.blockManager.port"
+	SparkDriverBlockManagerPort = "spark.driver.blockManager.port"
+	DotSeparator                = "."
+	SparkDriverDNSPolicy        = "ClusterFirst"
+	// SparkDriverHostNetworkDNSPolicy keeps cluster DNS resolution for a driver pod on the host network
+	SparkDriverHostNetworkDNSPolicy  = "ClusterFirstWithHostNet"
 	SparkNodeSelectorPrefix          = "spark.kubernetes.node.selector."
 	SparkDriverPodNodeSelectorPrefix = "spark.kubernetes.driver.node.selector."
 	DriverPodRestartPolicyNever      = "Never"
 	// LabelAnnotationPrefix is the prefix of every labels and annotations added by the controller.
 	LabelAnnotationPrefix = "sparkoperator.k8s.io/"
 	// SparkAppNameLabel is the name of the label for the SparkApplication object name.
-	SparkAppNameLabel                    = LabelAnnotationPrefix + "app-name"
+	SparkAppNameLabel = LabelAnnotationPrefix + "app-name"
+	// SubmissionIDLabel is the label that records the submission ID of the current run of an application.
+	SubmissionIDLabel                    = LabelAnnotationPrefix + "submission-id"
 	DriverPodSecurityContextID           = 185
 	DefaultTerminationGracePeriodSeconds = 30
 	TolerationEffect                     = "NoExecute"
@@ -33,6 +51,10 @@ const (
 	DefaultTolerationSeconds             = 300
 	SparkConfVolumeDriver                = "spark-conf-volume-driver"
 	SparkConfVolumeDriverMountPath       = "/opt/spark/conf"
+	ConfigMapVolumeExtension             = "-vol"
+	HadoopConfVolume                     = "hadoop-properties"
+	HadoopConfDir                        = "HADOOP_CONF_DIR"
+	HadoopConfDirPath                    = "/opt/hadoop/conf"
 	SparkEnvScriptFileName               = "spark-env.sh"
 	SparkPropertiesFileName              = "spark.properties"
 	LocalStoragePrefix                   = "spark-local-dir-"
@@ -66,4 +88,16 @@ const (
 	CaCertFile                           = "spark.kubernetes.authenticate.driver.caCertFile"
 	KubernetesCredentials                = "kubernetes-credentials"
 	KubernetesCredentialsVolumeMountPath = "/mnt/secrets/spark-kubernetes-credentials"
+	OAuthToken                           = "spark.kubernetes.authenticate.driver.oauthToken"
+	// The driver, submitted with spark.kubernetes.submitInDriver, authenticates with the mounted credentials
+	OAuthTokenMountedConfFile = "spark.kubernetes.authenticate.driver.mounted.oauthTokenFile"
+	ClientKeyMountedFile      = "spark.kubernetes.authenticate.driver.mounted.clientKeyFile"
+	ClientCertMountedFile     = "spark.kubernetes.authenticate.driver.mounted.clientCertFile"
+	CaCertMountedFile         = "spark.kubernetes.authenticate.driver.mounted.caCertFile"
+	// KubernetesCredentialsSecretExtension names the Secret the credentials are shipped in
+	KubernetesCredentialsSecretExtension = "-kubernetes-credentials"
+	// PodDeletionPollInterval is how often a deleted driver pod is checked for while waiting for it to disappear
+	PodDeletionPollInterval = time.Second
+	// PodDeletionTimeoutPadding is added to the deletion grace period to bound the wait for a deleted driver pod
+	PodDeletionTimeoutPadding = 30 * time.Second
 )
@@ -5,330 +5,198 @@ import (
 	"fmt"
 	"math"
 	"nativesubmit/common"
+	"nativesubmit/features"
+	"nativesubmit/internal/tracing"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/kubeflow/spark-operator/api/v1beta2"
+	"go.opentelemetry.io/otel/attribute"
 	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apiErrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/util/retry"
 	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 // Helper func to create Driver Pod of the Spark Application
-func Create(app *v1beta2.SparkApplication, serviceLabels map[string]string, driverConfigMapName string, kubeClient ctrlClient.Client, appSpecVolumeMounts []apiv1.VolumeMount, appSpecVolumes []apiv1.Volume) error {
-	if app == nil {
-		return fmt.Errorf("spark application cannot be nil")
+func Create(ctx context.Context, app *v1beta2.SparkApplication, serviceLabels map[string]string, driverConfigMapName string, kubeClient ctrlClient.Client, appSpecVolumeMounts []apiv1.VolumeMount, appSpecVolumes []apiv1.Volume) error {
+	driverPod, err := Build(ctx, app, serviceLabels, driverConfigMapName, appSpecVolumeMounts, appSpecVolumes)
+	if err != nil {
+		return err
 	}
+	_, err = CreateOrUpdate(tracing.WithApplication(ctx, app), driverPod, kubeClient, common.SubmitOptions{})
+	return err
+}
+
+// Build renders the Driver Pod of the Spark Application without calling the API server
+func Build(ctx context.Context, app *v1beta2.SparkApplication, serviceLabels map[string]string, driverConfigMapName string, appSpecVolumeMounts []apiv1.VolumeMount, appSpecVolumes []apiv1.Volume) (*apiv1.Pod, error) {
+	driverPod, _, err := BuildWithFeatureSteps(ctx, &features.DriverConf{
+		App:                 app,
+		ConfigMapName:       driverConfigMapName,
+		Labels:              serviceLabels,
+		AppSpecVolumeMounts: appSpecVolumeMounts,
+		AppSpecVolumes:      appSpecVolumes,
+	}, nil)
+	return driverPod, err
+}
+
+// BuildWithFeatureSteps renders the Driver Pod by running the built-in feature steps followed by customSteps, and
+// returns it with the Spark properties and additional resources the steps contributed
+func BuildWithFeatureSteps(ctx context.Context, conf *features.DriverConf, customSteps []features.Step) (*apiv1.Pod, *features.Contributions, error) {
+	if conf == nil || conf.App == nil {
+		return nil, nil, fmt.Errorf("spark application cannot be nil")
+	}
+	app := conf.App
 	//Load template file, if one supplied
 	var initialPod apiv1.Pod
-	var err error
 	driverPodtemplateFile, templateFileExists := app.Spec.SparkConf["spark.kubernetes.driver.podTemplateFile"]
 	if templateFileExists {
+		var err error
 		podTemplateDriverContainerName := app.Spec.SparkConf["spark.kubernetes.driver.podTemplateContainerName"]
-		initialPod, err = loadPodFromTemplate(driverPodtemplateFile, podTemplateDriverContainerName, app.Spec.SparkConf)
+		initialPod, err = loadPodFromTemplate(ctx, driverPodtemplateFile, podTemplateDriverContainerName, app.Spec.SparkConf)
 		if err != nil {
-			return fmt.Errorf("failed to load template file for the driver pod %s in namespace %s: %v", common.GetDriverPodName(app), app.Namespace, err)
-
-		}
-	}
-	//Driver pod spec instance
-	var driverPodSpec apiv1.PodSpec
-	if templateFileExists {
-		driverPodSpec = initialPod.Spec
-	}
-
-	// Spark Application Driver Pod schema populating with specific values/data
-	var podObjectMetadata metav1.ObjectMeta
-	//Driver Pod Name
-	podObjectMetadata.Name = common.GetDriverPodName(app)
-	//Driver pod Namespace
-	podObjectMetadata.Namespace = common.GetAppNamespace(app)
-	//Driver Pod labels
-	podObjectMetadata.Labels = serviceLabels
-	//Driver pod annotations
-	if app.Spec.Driver.Annotations != nil {
-		podObjectMetadata.Annotations = app.Spec.Driver.Annotations
-	} else {
-		annotations := make(map[string]string)
-		for sparkConfKey, sparkConfValue := range app.Spec.SparkConf {
-			if strings.Contains(sparkConfKey, "spark.kubernetes.driver.annotation.") {
-				lastDotIndex := strings.LastIndex(sparkConfKey, DotSeparator)
-				annotationKey := sparkConfKey[lastDotIndex+1:]
-				annotations[annotationKey] = sparkConfValue
-			}
-		}
-		if len(annotations) > 0 {
-			podObjectMetadata.Annotations = annotations
-		}
-	}
-	//Driver Pod Owner Reference
-	podObjectMetadata.OwnerReferences = []metav1.OwnerReference{*common.GetOwnerReference(app)}
-
-	//Driver pod DNS policy
-	driverPodSpec.DNSPolicy = SparkDriverDNSPolicy
-
-	//Driver pod enable service link
-	driverPodSpec.EnableServiceLinks = common.BoolPointer(true)
-
-	//Priority wise, values mentioned in Spec i.e. app.Spec.NodeSelector or/and app.Spec.Driver.NodeSelector will be higher than
-	// ones specified in sparkConf
-
-	if app.Spec.NodeSelector != nil {
-		driverPodSpec.NodeSelector = app.Spec.NodeSelector
-	} else if app.Spec.Driver.NodeSelector != nil {
-		//for key, value := range app.Spec.Driver.NodeSelector {
-		//	driverPodSpec.NodeSelector[key] = value
-		//}
-		driverPodSpec.NodeSelector = app.Spec.Driver.NodeSelector
-	} else {
-		nodeSelectorList := make(map[string]string)
-		//Driver pod node selector
-		for sparkConfKey, sparkConfValue := range app.Spec.SparkConf {
-			if strings.Contains(sparkConfKey, SparkNodeSelectorPrefix) {
-				lastDotIndex := strings.LastIndex(sparkConfKey, DotSeparator)
-				driverPodNodeSelectorKey := sparkConfKey[lastDotIndex+1:]
-				nodeSelectorList[driverPodNodeSelectorKey] = sparkConfValue
-			}
-		}
-		for sparkConfKey, sparkConfValue := range app.Spec.SparkConf {
-			if strings.Contains(sparkConfKey, SparkDriverPodNodeSelectorPrefix) {
-				lastDotIndex := strings.LastIndex(sparkConfKey, DotSeparator)
-				driverPodNodeSelectorKey := sparkConfKey[lastDotIndex+1:]
-				nodeSelectorList[driverPodNodeSelectorKey] = sparkConfValue
-			}
-		}
-		if len(nodeSelectorList) > 0 {
-			driverPodSpec.NodeSelector = nodeSelectorList
-		}
-	}
-
-	//Image pull Secrets
-	var imagePullSecrets []string
-	if app.Spec.ImagePullSecrets != nil {
-		imagePullSecrets = app.Spec.ImagePullSecrets
-	} else if common.CheckSparkConf(app.Spec.SparkConf, common.SparkImagePullSecretKey) {
-		imagePullSecretList := app.Spec.SparkConf[common.SparkImagePullSecretKey]
-		imagePullSecrets = strings.Split(imagePullSecretList, ",")
-	}
-	var imagePullSecretsList []apiv1.LocalObjectReference
-	for _, secretName := range imagePullSecrets {
-		var imagePullSecret apiv1.LocalObjectReference
-		imagePullSecret.Name = secretName
-		imagePullSecretsList = append(imagePullSecretsList, imagePullSecret)
-	}
-	driverPodSpec.ImagePullSecrets = imagePullSecretsList
-
-	//RestartPolicy
-	driverPodSpec.RestartPolicy = DriverPodRestartPolicyNever
-	//Driver pod security context
-	if app.Spec.Driver.SecurityContext != nil {
-		if app.Spec.Driver.SecurityContext.RunAsUser != nil || app.Spec.Driver.SecurityContext.RunAsNonRoot != nil {
-			var podSecurityContext apiv1.PodSecurityContext
-			if app.Spec.Driver.SecurityContext.RunAsUser != nil {
-				podSecurityContext.RunAsUser = app.Spec.Driver.SecurityContext.RunAsUser
-				podSecurityContext.FSGroup = app.Spec.Driver.SecurityContext.RunAsUser
-				podSecurityContext.SupplementalGroups = SupplementalGroups(*app.Spec.Driver.SecurityContext.RunAsUser)
-			}
-			if app.Spec.Driver.SecurityContext.RunAsNonRoot != nil {
-				podSecurityContext.RunAsNonRoot = app.Spec.Driver.SecurityContext.RunAsNonRoot
-			}
-
-			driverPodSpec.SecurityContext = &podSecurityContext
-		}
-	} else {
-		driverPodSpec.SecurityContext = &apiv1.PodSecurityContext{
-			RunAsUser: common.Int64Pointer(DriverPodSecurityContextID),
-			FSGroup:   common.Int64Pointer(DriverPodSecurityContextID),
-			//Run as non-root
-			RunAsNonRoot:       common.BoolPointer(true),
-			SupplementalGroups: SupplementalGroups(DriverPodSecurityContextID),
+			return nil, nil, fmt.Errorf("failed to load template file for the driver pod %s in namespace %s: %v", common.GetDriverPodName(app), app.Namespace, err)
 		}
 	}
-	//Service Account
-	if app.Spec.Driver.ServiceAccount != nil {
-		driverPodSpec.ServiceAccountName = *app.Spec.Driver.ServiceAccount
-	} else if common.CheckSparkConf(app.Spec.SparkConf, "spark.kubernetes.authenticate.driver.serviceAccountName") {
-		driverPodSpec.ServiceAccountName = app.Spec.SparkConf["spark.kubernetes.authenticate.driver.serviceAccountName"]
-	}
-	//Pod Scheduler Name
-	driverPodSchedulerName, driverPodSchedulerValueExists := app.Spec.SparkConf["spark.kubernetes.driver.scheduler.name"]
-	podSchedulerName, podSchedulerValueExists := app.Spec.SparkConf["spark.kubernetes.scheduler.name"]
-	if driverPodSchedulerValueExists {
-		driverPodSpec.SchedulerName = driverPodSchedulerName
-	} else if podSchedulerValueExists {
-		driverPodSpec.SchedulerName = podSchedulerName
-	}
 
-	//Termination grace period
-	if app.Spec.Driver.TerminationGracePeriodSeconds != nil {
-		driverPodSpec.TerminationGracePeriodSeconds = app.Spec.Driver.TerminationGracePeriodSeconds
-	} else {
-		driverPodSpec.TerminationGracePeriodSeconds = common.Int64Pointer(DefaultTerminationGracePeriodSeconds)
-	}
-	//Tolerations
-	if app.Spec.Driver.Tolerations != nil {
-		driverPodSpec.Tolerations = app.Spec.Driver.Tolerations
-	} else {
-		//Assigning default toleration
-		var tolerations []apiv1.Toleration
-		tolerations = []apiv1.Toleration{
-			{
-				Effect:            TolerationEffect,
-				Key:               NodeNotReady,
-				Operator:          Operator,
-				TolerationSeconds: common.Int64Pointer(DefaultTolerationSeconds),
-			},
-			{
-				Effect:            TolerationEffect,
-				Key:               NodeNotReachable,
-				Operator:          Operator,
-				TolerationSeconds: common.Int64Pointer(DefaultTolerationSeconds),
-			},
-		}
-		driverPodSpec.Tolerations = tolerations
-	}
-
-	//Pod Volumes setup
-	var driverPodVolumes []apiv1.Volume
-
-	// spark-conf-volume-driver addition
-	sparkConfVolume := apiv1.Volume{
-		Name: SparkConfVolumeDriver,
-		VolumeSource: apiv1.VolumeSource{
-			ConfigMap: &apiv1.ConfigMapVolumeSource{
-				DefaultMode: Int32Pointer(420),
-				Items: []apiv1.KeyToPath{
-					{
-						Key:  SparkEnvScriptFileName,
-						Mode: Int32Pointer(420),
-						Path: SparkEnvScriptFileName,
-					},
-					{
-						Key:  SparkPropertiesFileName,
-						Mode: Int32Pointer(420),
-						Path: SparkPropertiesFileName,
-					},
-				},
-				LocalObjectReference: apiv1.LocalObjectReference{Name: driverConfigMapName},
+	// Only the pod level settings of the template are kept, the steps build the containers and volumes
+	driverPodSpec := initialPod.Spec
+	driverPodSpec.Containers = nil
+	driverPodSpec.Volumes = nil
+	sparkPod := &features.SparkPod{
+		Pod: &apiv1.Pod{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: apiv1.SchemeGroupVersion.String(),
+				Kind:       "Pod",
 			},
+			Spec: driverPodSpec,
 		},
+		Container: &apiv1.Container{},
 	}
 
-	driverPodVolumes = append(driverPodVolumes, sparkConfVolume)
-
-	driverPodContainerSpec, resolvedLocalDirs := CreateDriverPodContainerSpec(app)
-	var containerSpecList []apiv1.Container
-	localDirFeatureSetupError := handleLocalDirsFeatureStep(app, resolvedLocalDirs, &driverPodVolumes, &driverPodContainerSpec.VolumeMounts, &driverPodContainerSpec.Env, appSpecVolumeMounts, appSpecVolumes)
-	if localDirFeatureSetupError != nil {
-		return fmt.Errorf("failed to setup local directory for the driver pod %s in namespace %s: %v", common.GetDriverPodName(app), app.Namespace, localDirFeatureSetupError)
+	steps := append(BuiltinSteps(), customSteps...)
+	contributions, err := features.Run(ctx, conf, sparkPod, steps)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	volumeExtension := "-volume"
-	if app.Spec.Driver.Secrets != nil {
-		for _, secret := range app.Spec.Driver.Secrets {
-			driverPodVolumes, driverPodContainerSpec = addSecret(secret, volumeExtension, driverPodVolumes, driverPodContainerSpec)
+	// The steps append env vars the driver spec may also set, such as SPARK_CONF_DIR and SPARK_LOCAL_DIRS
+	sparkPod.Container.Env = collapseEnvVars(sparkPod.Container.Env)
+	driverPod := sparkPod.Pod
+	driverPod.Spec.Containers = append([]apiv1.Container{*sparkPod.Container}, driverPod.Spec.Containers...)
+
+	for _, resource := range contributions.AdditionalResources {
+		if resource.GetNamespace() == "" {
+			resource.SetNamespace(driverPod.Namespace)
 		}
-	}
-	//Populating secrets passed in sparkConf
-	for sparkConfKey, sparkConfValue := range app.Spec.SparkConf {
-		if strings.Contains(sparkConfKey, common.SparkDriverSecretKeyPrefix) {
-			lastDotIndex := strings.LastIndex(sparkConfKey, DotSeparator)
-			driverPodSecretKey := sparkConfKey[lastDotIndex+1:]
-			var secret v1beta2.SecretInfo
-			secret.Name = driverPodSecretKey
-			secret.Path = sparkConfValue
-			driverPodVolumes, driverPodContainerSpec = addSecret(secret, volumeExtension, driverPodVolumes, driverPodContainerSpec)
+		if len(resource.GetOwnerReferences()) == 0 {
+			resource.SetOwnerReferences([]metav1.OwnerReference{*common.GetOwnerReference(app)})
 		}
 	}
-	containerSpecList = append(containerSpecList, driverPodContainerSpec)
-
-	containerSpecList = handleSideCars(app, containerSpecList, appSpecVolumes)
-
-	if app.Spec.Driver.InitContainers != nil {
-		driverPodSpec.InitContainers = app.Spec.Driver.InitContainers
-	}
-
-	driverPodSpec.Containers = containerSpecList
-
-	// Setting up pod volumes
-	driverPodSpec.Volumes = driverPodVolumes
-
-	driverPod := &apiv1.Pod{
-		ObjectMeta: podObjectMetadata,
-		Spec:       driverPodSpec,
-	}
+	return driverPod, contributions, nil
+}
 
+// CreateOrUpdate submits a rendered Driver Pod to the API server and reports whether it was created.
+// Pod specs are immutable, so an existing pod is reused only when it belongs to the same submission; a pod left
+// behind by a previous submission is deleted, waited for and replaced by the rendered one. A pod owned by a
+// different Spark Application is left alone unless ownership takeover is allowed, in which case a pod of the same
+// submission is adopted in place. With server-side apply the new pod
+// is applied rather than created, so it is owned by the native-submit field manager.
+func CreateOrUpdate(ctx context.Context, driverPod *apiv1.Pod, kubeClient ctrlClient.Client, opts common.SubmitOptions) (operationResult controllerutil.OperationResult, err error) {
+	ctx, span := tracing.StartSpan(ctx, "driver.CreateOrUpdate", attribute.String("k8s.pod.name", driverPod.Name))
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
+
+	operationResult = controllerutil.OperationResultNone
 	//Check existence of pod
-	createPodErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+	createPodErr := retry.OnError(retry.DefaultRetry, common.IsRetriableConflict, func() error {
 		existingDriverPod := &apiv1.Pod{}
-		err := kubeClient.Get(context.TODO(), ctrlClient.ObjectKeyFromObject(driverPod), existingDriverPod)
-		//driverPodExisting, err := kubeClient.CoreV1().Pods(app.Namespace).Get(context.TODO(), podObjectMetadata.Name, metav1.GetOptions{})
-		if apiErrors.IsNotFound(err) {
-			createErr := kubeClient.Create(context.TODO(), driverPod)
-			//_, createErr := kubeClient.CoreV1().Pods(app.Namespace).Create(context.TODO(), driverPod, metav1.CreateOptions{})
-
-			if createErr != nil {
-				return fmt.Errorf("error while creating driver pod: %w", createErr)
+		err := kubeClient.Get(ctx, ctrlClient.ObjectKeyFromObject(driverPod), existingDriverPod)
+		if err == nil {
+			reusable := existingDriverPod.DeletionTimestamp == nil && isSameSubmission(existingDriverPod, driverPod)
+			previousMeta := existingDriverPod.ObjectMeta.DeepCopy()
+			if err := common.AdoptExisting(existingDriverPod, driverPod, "pods", opts.AllowOwnershipTakeover); err != nil {
+				return err
 			}
-			return nil
-		}
-		if err != nil {
+			if reusable {
+				// The pod of this submission is kept, only its metadata is updated when it is taken over
+				if equality.Semantic.DeepEqual(*previousMeta, existingDriverPod.ObjectMeta) {
+					operationResult = controllerutil.OperationResultNone
+					return nil
+				}
+				if updateErr := kubeClient.Update(ctx, existingDriverPod); updateErr != nil {
+					return fmt.Errorf("error while updating driver pod: %w", updateErr)
+				}
+				operationResult = controllerutil.OperationResultUpdated
+				return nil
+			}
+			replaceErr := deletePodAndWait(ctx, existingDriverPod, kubeClient, opts.DriverPodDeletionGracePeriodSeconds)
+			if replaceErr != nil {
+				return fmt.Errorf("error while replacing driver pod of submission %q: %w", existingDriverPod.Labels[SubmissionIDLabel], replaceErr)
+			}
+		} else if !apiErrors.IsNotFound(err) {
 			return fmt.Errorf("error while retrieving driver pod: %w", err)
-			//return err
-		}
-		existingDriverPod.ObjectMeta = podObjectMetadata
-		existingDriverPod.Spec = driverPodSpec
-		updateErr := kubeClient.Update(context.TODO(), existingDriverPod)
-		if updateErr != nil {
-			return fmt.Errorf("error while updating driver pod: %w", updateErr)
 		}
 
-		return updateErr
+		if opts.ApplyMode == common.ApplyModeServerSide {
+			applyResult, applyErr := common.ServerSideApply(ctx, kubeClient, driverPod, "pods", opts)
+			if applyErr != nil {
+				return applyErr
+			}
+			operationResult = applyResult
+			return nil
+		}
+		createErr := kubeClient.Create(ctx, driverPod)
+		if createErr != nil {
+			return fmt.Errorf("error while creating driver pod: %w", createErr)
+		}
+		operationResult = controllerutil.OperationResultCreated
+		return nil
 	})
 
 	if createPodErr != nil {
-		return fmt.Errorf("failed to create/update driver pod %s in namespace %s: %v", common.GetDriverPodName(app), app.Namespace, createPodErr)
+		return operationResult, fmt.Errorf("failed to create/update driver pod %s in namespace %s: %w", driverPod.Name, driverPod.Namespace, createPodErr)
 	}
 
-	return nil
+	return operationResult, nil
+}
+
+// isSameSubmission Helper func to check whether an existing driver pod was created by the submission of the rendered one
+func isSameSubmission(existingDriverPod *apiv1.Pod, driverPod *apiv1.Pod) bool {
+	submissionID := driverPod.Labels[SubmissionIDLabel]
+	return submissionID != "" && existingDriverPod.Labels[SubmissionIDLabel] == submissionID
 }
 
+// handleSideCars Helper func to add the sidecars of the driver spec. A sidecar keeps the volume mounts that refer to a
+// volume of the app spec or to the volume of a driver config map, "<configmap>-vol", which is mounted at the path
+// of the config map when the mount does not set one.
 func handleSideCars(app *v1beta2.SparkApplication, containerSpecList []apiv1.Container, appSpecVolumes []apiv1.Volume) []apiv1.Container {
-	if app.Spec.Driver.Sidecars != nil {
-		var sideCarVolumeMounts []apiv1.VolumeMount
-		for _, configMap := range app.Spec.Driver.ConfigMaps {
-			//Volume mount for the configmap
-			volumeMount := apiv1.VolumeMount{
-				Name:      configMap.Name + "-vol",
-				MountPath: configMap.Path,
-			}
-			sideCarVolumeMounts = append(sideCarVolumeMounts, volumeMount)
-		}
+	configMapPaths := make(map[string]string)
+	for _, configMap := range app.Spec.Driver.ConfigMaps {
+		configMapPaths[configMap.Name+ConfigMapVolumeExtension] = configMap.Path
+	}
 
-		for _, sideCarContainer := range app.Spec.Driver.Sidecars {
-			//Handle volume mounts
-			for _, volumeTobeMounted := range sideCarContainer.VolumeMounts {
-				//Volume mount for the volumes
-				if checkVolumeMountIsVolume(appSpecVolumes, volumeTobeMounted.Name) {
-					volumeMount := apiv1.VolumeMount{
-						Name:      volumeTobeMounted.Name,
-						MountPath: volumeTobeMounted.MountPath,
-						ReadOnly:  volumeTobeMounted.ReadOnly,
-					}
-					sideCarVolumeMounts = append(sideCarVolumeMounts, volumeMount)
+	for _, sideCarContainer := range app.Spec.Driver.Sidecars {
+		var sideCarVolumeMounts []apiv1.VolumeMount
+		for _, volumeTobeMounted := range sideCarContainer.VolumeMounts {
+			if configMapPath, isConfigMap := configMapPaths[volumeTobeMounted.Name]; isConfigMap {
+				if volumeTobeMounted.MountPath == "" {
+					volumeTobeMounted.MountPath = configMapPath
 				}
+				sideCarVolumeMounts = append(sideCarVolumeMounts, volumeTobeMounted)
+			} else if checkVolumeMountIsVolume(appSpecVolumes, volumeTobeMounted.Name) {
+				sideCarVolumeMounts = append(sideCarVolumeMounts, volumeTobeMounted)
 			}
-			sideCarContainer.VolumeMounts = nil
-			if len(sideCarVolumeMounts) > 0 {
-				sideCarContainer.VolumeMounts = sideCarVolumeMounts
-			}
-
-			containerSpecList = append(containerSpecList, sideCarContainer)
 		}
+		sideCarContainer.VolumeMounts = sideCarVolumeMounts
+		containerSpecList = append(containerSpecList, sideCarContainer)
 	}
 	return containerSpecList
 }
@@ -343,7 +211,7 @@ func checkVolumeMountIsVolume(appSpecVolumes []apiv1.Volume, volumeName string)
 }
 
 // CreateDriverPodContainerSpec Helper func to create Driver Pod Driver contianer spec creation
-func CreateDriverPodContainerSpec(app *v1beta2.SparkApplication) (apiv1.Container, []string) {
+func CreateDriverPodContainerSpec(app *v1beta2.SparkApplication) (apiv1.Container, []string, error) {
 	var driverPodContainerSpec apiv1.Container
 	mainClass := ""
 	mainApplicationFile := ""
@@ -394,29 +262,19 @@ func CreateDriverPodContainerSpec(app *v1beta2.SparkApplication) (apiv1.Containe
 	}
 	driverPodContainerEnvVars = append(driverPodContainerEnvVars, driverPodContainerEnvVarBindAddress)
 
-	// Add all the spark.kubernetes.driverEnv. prefixed sparkConf key value pairs
+	// Add the env vars of sparkConf and of the driver spec
 	var resolvedLocalDirs []string
 	resolvedLocalDirs, driverPodContainerEnvVars = processSparkConfEnv(app, driverPodContainerEnvVars)
 	sparkConfKeyValuePairs := app.Spec.SparkConf
 
-	// Addition of the spark.kubernetes.kerberos.tokenSecret.itemKey
-	// Handling https://spark.apache.org/docs/3.0.0-preview2/security.html#long-running-applications
-	//spark.kubernetes.kerberos.tokenSecret.name spark.kubernetes.kerberos.tokenSecret.itemKey
-	sparkConfValue, valueExists := sparkConfKeyValuePairs[KerberosTokenSecretItemKey]
-	if valueExists {
-		var driverPodContainerEnvVar apiv1.EnvVar
-		driverPodContainerEnvVar.Name = KerberosHadoopSecretFilePathKey
-		driverPodContainerEnvVar.Value = KerberosHadoopSecretFilePath + sparkConfValue
-		driverPodContainerEnvVars = append(driverPodContainerEnvVars, driverPodContainerEnvVar)
-	}
-
-	//Spark Config directory
+	//Spark Config directory, appended after the user env vars so it wins over them
 	var sparkConfigDir apiv1.EnvVar
 	sparkConfigDir.Name = common.SparkConfDirEnvVar
 	sparkConfigDir.Value = SparkConfVolumeDriverMountPath
 	driverPodContainerEnvVars = append(driverPodContainerEnvVars, sparkConfigDir)
 	//Assign the Driver Pod Container Environment variables to Container Spec
 	driverPodContainerSpec.Env = driverPodContainerEnvVars
+	driverPodContainerSpec.EnvFrom = app.Spec.Driver.EnvFrom
 
 	//Assign Driver Pod container image from Spec or from sparkConf
 	if app.Spec.Driver.Image != nil {
@@ -443,14 +301,23 @@ func CreateDriverPodContainerSpec(app *v1beta2.SparkApplication) (apiv1.Containe
 	driverPodContainerSpec.Name = common.SparkDriverContainerName
 
 	//Driver pod contianer ports
-	driverPodContainerSpec.Ports = []apiv1.ContainerPort{
+	driverPort, err := common.GetDriverPort(sparkConfKeyValuePairs)
+	if err != nil {
+		return driverPodContainerSpec, nil, err
+	}
+	blockManagerPort, err := getBlockManagerPort(sparkConfKeyValuePairs)
+	if err != nil {
+		return driverPodContainerSpec, nil, err
+	}
+	// A port of 0 lets Spark pick a random port, which cannot be declared on the container
+	for _, port := range []apiv1.ContainerPort{
 		{
-			ContainerPort: int32(common.GetDriverPort(sparkConfKeyValuePairs)),
+			ContainerPort: int32(driverPort),
 			Name:          DriverPortName,
 			Protocol:      Protocol,
 		},
 		{
-			ContainerPort: int32(getBlockManagerPort(sparkConfKeyValuePairs)),
+			ContainerPort: int32(blockManagerPort),
 			Name:          BlockManagerPortName,
 			Protocol:      Protocol,
 		},
@@ -459,10 +326,18 @@ func CreateDriverPodContainerSpec(app *v1beta2.SparkApplication) (apiv1.Containe
 			Name:          UiPortName,
 			Protocol:      Protocol,
 		},
+	} {
+		if port.ContainerPort != 0 {
+			driverPodContainerSpec.Ports = append(driverPodContainerSpec.Ports, port)
+		}
 	}
 
 	//Driver pod container cpu and memory requests and limits populating
-	driverPodContainerSpec.Resources = handleResources(app)
+	resources, err := handleResources(app)
+	if err != nil {
+		return driverPodContainerSpec, nil, err
+	}
+	driverPodContainerSpec.Resources = resources
 
 	//Security Context
 	driverPodContainerSpec.SecurityContext = &apiv1.SecurityContext{
@@ -485,24 +360,9 @@ func CreateDriverPodContainerSpec(app *v1beta2.SparkApplication) (apiv1.Containe
 	}
 	volumeMounts = append(volumeMounts, volumeMount)
 
-	volumeMounts = handleKerberoCreds(app, volumeMounts)
-
 	driverPodContainerSpec.VolumeMounts = volumeMounts
 
-	return driverPodContainerSpec, resolvedLocalDirs
-}
-
-func checkMountingKubernetesCredentials(sparkConfKeyValuePairs map[string]string) bool {
-	if sparkConfKeyValuePairs != nil {
-		OAuthTokenConfFileExists := sparkConfKeyValuePairs[OAuthTokenConfFile] != ""
-		ClientKeyFileExists := sparkConfKeyValuePairs[ClientKeyFile] != ""
-		ClientCertFileExists := sparkConfKeyValuePairs[ClientCertFile] != ""
-		CaCertFileExists := sparkConfKeyValuePairs[CaCertFile] != ""
-		if OAuthTokenConfFileExists || ClientKeyFileExists || ClientCertFileExists || CaCertFileExists {
-			return true
-		}
-	}
-	return false
+	return driverPodContainerSpec, resolvedLocalDirs, nil
 }
 
 func incorporateMemoryOvehead(memoryNumber int, app *v1beta2.SparkApplication, memoryUnit string) string {
@@ -565,88 +425,108 @@ func processMemoryUnit(memoryData string) int {
 	return memoryInMiB
 }
 
+// processSparkConfEnv Helper func to append the user defined env vars of the driver to driverPodContainerEnvVars and
+// to get the local dirs of SPARK_LOCAL_DIRS. A variable set by more than one source takes the value of the last of:
+//  1. spark.kubernetes.driverEnv.<NAME>
+//  2. spark.kubernetes.driver.secretKeyRef.<NAME>=<secret>:<key>
+//  3. spec.driver.envVars
+//  4. spec.driver.envSecretKeyRefs
+//  5. spec.driver.env, the last of its entries with the same name
+//
+// The variables of the first four sources are appended in name order, followed by spec.driver.env in its own order,
+// so that its entries can refer to any of the others. Duplicates are collapsed by collapseEnvVars once the driver
+// container is built. SPARK_CONF_DIR and SPARK_LOCAL_DIRS are the exception: the driver needs them to point at the
+// volumes native submit mounts, so the values appended after these ones win over any source. A SPARK_LOCAL_DIRS set
+// here still decides which local dirs are mounted.
 func processSparkConfEnv(app *v1beta2.SparkApplication, driverPodContainerEnvVars []apiv1.EnvVar) ([]string, []apiv1.EnvVar) {
-	var resolvedLocalDirs []string
-	sparkConfKeyValuePairs := app.Spec.SparkConf
-	var driverPodContainerEnvConfigVars apiv1.EnvVar
-	for sparkConfKey, sparkConfValue := range sparkConfKeyValuePairs {
-		if strings.Contains(sparkConfKey, SparkDriverEnvPrefix) {
-			lastDotIndex := strings.LastIndex(sparkConfKey, DotSeparator)
-			driverPodContainerEnvConfigVarKey := sparkConfKey[lastDotIndex+1:]
-			if driverPodContainerEnvConfigVarKey == "SPARK_LOCAL_DIRS" {
-				resolvedLocalDirs = strings.Split(sparkConfValue, ",")
-			}
-			driverPodContainerEnvConfigVars.Name = driverPodContainerEnvConfigVarKey
-			driverPodContainerEnvConfigVars.Value = sparkConfValue
-			driverPodContainerEnvVars = append(driverPodContainerEnvVars, driverPodContainerEnvConfigVars)
-		}
-	}
-
-	// Add envVars of Driver portion to Driver Pod Spec
-	sparkDriverConfKeyValuePairs := app.Spec.Driver.EnvVars
-	if sparkDriverConfKeyValuePairs != nil {
-		var driverPodContainerEnvVar apiv1.EnvVar
-		for sparkDriverEnvKey, sparkDriverEnvValue := range sparkDriverConfKeyValuePairs {
-			driverPodContainerEnvVar.Name = sparkDriverEnvKey
-			driverPodContainerEnvVar.Value = sparkDriverEnvValue
-			driverPodContainerEnvVars = append(driverPodContainerEnvVars, driverPodContainerEnvVar)
+	envVars := make(map[string]apiv1.EnvVar)
+	for sparkConfKey, sparkConfValue := range app.Spec.SparkConf {
+		if envName, found := strings.CutPrefix(sparkConfKey, SparkDriverEnvPrefix+DotSeparator); found {
+			envVars[envName] = apiv1.EnvVar{Name: envName, Value: sparkConfValue}
 		}
 	}
-	//spark.kubernetes.driver.secretKeyRef.
-	var driverPodContainerEnvVar apiv1.EnvVar
 	for sparkConfKey, sparkConfValue := range app.Spec.SparkConf {
-		if strings.Contains(sparkConfKey, common.SparkDriverSecretKeyRefKeyPrefix) {
-			lastDotIndex := strings.LastIndex(sparkConfKey, DotSeparator)
-			driverPodSecretKeyRef := sparkConfKey[lastDotIndex+1:]
-			driverPodContainerEnvVar.Name = driverPodSecretKeyRef
-			driverPodContainerEnvVar.Value = sparkConfValue
-			driverPodContainerEnvVars = append(driverPodContainerEnvVars, driverPodContainerEnvVar)
+		if envName, found := strings.CutPrefix(sparkConfKey, common.SparkDriverSecretKeyRefKeyPrefix); found {
+			secretName, secretKey, _ := strings.Cut(sparkConfValue, SecretKeyRefSeparator)
+			envVars[envName] = secretKeyRefEnvVar(envName, secretName, secretKey)
 		}
 	}
-	return resolvedLocalDirs, driverPodContainerEnvVars
-}
+	for envName, envValue := range app.Spec.Driver.EnvVars {
+		envVars[envName] = apiv1.EnvVar{Name: envName, Value: envValue}
+	}
+	for envName, nameKey := range app.Spec.Driver.EnvSecretKeyRefs {
+		envVars[envName] = secretKeyRefEnvVar(envName, nameKey.Name, nameKey.Key)
+	}
+	for _, envVar := range app.Spec.Driver.Env {
+		delete(envVars, envVar.Name)
+	}
 
-func handleKerberoCreds(app *v1beta2.SparkApplication, volumeMounts []apiv1.VolumeMount) []apiv1.VolumeMount {
-	sparkConfKeyValuePairs := app.Spec.SparkConf
-	if sparkConfKeyValuePairs != nil {
-		_, kerberosPatheExists := sparkConfKeyValuePairs[KerberosPath]
-		_, kerberosConfigMapNameExists := sparkConfKeyValuePairs[KerberosConfigMapName]
-		if kerberosPatheExists || kerberosConfigMapNameExists {
-			kerberosConfigMapVolumeMount := apiv1.VolumeMount{
-				Name:      KerberosFileVolume,
-				MountPath: KerberosFileDirectoryPath + ForwardSlash + KerberosFileName,
-				SubPath:   KerberosFileName,
-			}
-			volumeMounts = append(volumeMounts, kerberosConfigMapVolumeMount)
-		}
+	envNames := make([]string, 0, len(envVars))
+	for envName := range envVars {
+		envNames = append(envNames, envName)
+	}
+	sort.Strings(envNames)
+	userEnvVars := make([]apiv1.EnvVar, 0, len(envNames)+len(app.Spec.Driver.Env))
+	for _, envName := range envNames {
+		userEnvVars = append(userEnvVars, envVars[envName])
 	}
+	userEnvVars = append(userEnvVars, app.Spec.Driver.Env...)
 
-	if checkMountingKubernetesCredentials(app.Spec.SparkConf) {
-		kubernetesCredentialsVolumeMount := apiv1.VolumeMount{
-			Name:      KubernetesCredentials,
-			MountPath: KubernetesCredentialsVolumeMountPath,
+	var resolvedLocalDirs []string
+	for _, envVar := range userEnvVars {
+		if envVar.Name == SparkLocalDir && envVar.ValueFrom == nil {
+			resolvedLocalDirs = strings.Split(envVar.Value, ",")
 		}
-		volumeMounts = append(volumeMounts, kubernetesCredentialsVolumeMount)
 	}
-	return volumeMounts
+	return resolvedLocalDirs, append(driverPodContainerEnvVars, userEnvVars...)
 }
 
-func handleResources(app *v1beta2.SparkApplication) apiv1.ResourceRequirements {
+// collapseEnvVars Helper func to keep one env var per name, with the value of the last one, as Kubernetes resolves a
+// duplicated name to. It keeps the position of the first one, so the env vars referring to it with $(NAME) still
+// follow it.
+func collapseEnvVars(envVars []apiv1.EnvVar) []apiv1.EnvVar {
+	positions := make(map[string]int, len(envVars))
+	collapsed := make([]apiv1.EnvVar, 0, len(envVars))
+	for _, envVar := range envVars {
+		if position, exists := positions[envVar.Name]; exists {
+			collapsed[position] = envVar
+			continue
+		}
+		positions[envVar.Name] = len(collapsed)
+		collapsed = append(collapsed, envVar)
+	}
+	return collapsed
+}
+
+// secretKeyRefEnvVar Helper func to build an env var taken from the key of a secret
+func secretKeyRefEnvVar(envName string, secretName string, secretKey string) apiv1.EnvVar {
+	return apiv1.EnvVar{
+		Name: envName,
+		ValueFrom: &apiv1.EnvVarSource{
+			SecretKeyRef: &apiv1.SecretKeySelector{LocalObjectReference: apiv1.LocalObjectReference{Name: secretName}, Key: secretKey},
+		},
+	}
+}
+
+func handleResources(app *v1beta2.SparkApplication) (apiv1.ResourceRequirements, error) {
 	var driverPodResourceRequirement apiv1.ResourceRequirements
 	var memoryQuantity resource.Quantity
 	var memoryInBytes string
+	var err error
 	memoryValExists := false
 	cpuValExists := false
 	//Memory Request
-	if app.Spec.Driver.Memory != nil {
-		memoryData := *app.Spec.Driver.Memory
+	if app.Spec.Driver.Memory != nil || common.CheckSparkConf(app.Spec.SparkConf, SparkDriverMemory) {
+		memoryData := app.Spec.SparkConf[SparkDriverMemory]
+		if app.Spec.Driver.Memory != nil {
+			memoryData = *app.Spec.Driver.Memory
+		}
 		// Identify memory unit and convert everything in MiB for uniformity
 		memoryInMiB := processMemoryUnit(memoryData)
 		memoryInBytes = incorporateMemoryOvehead(memoryInMiB, app, "Mi")
-		memoryQuantity = resource.MustParse(memoryInBytes)
-		memoryValExists = true
-	} else if common.CheckSparkConf(app.Spec.SparkConf, SparkDriverMemory) {
-		memoryQuantity = resource.MustParse(app.Spec.SparkConf[SparkDriverMemory])
+		if memoryQuantity, err = resource.ParseQuantity(memoryInBytes); err != nil {
+			return driverPodResourceRequirement, fmt.Errorf("invalid driver memory %q: %w", memoryData, err)
+		}
 		memoryValExists = true
 	} else { //setting default value
 		memoryQuantity = resource.MustParse("1")
@@ -654,10 +534,12 @@ func handleResources(app *v1beta2.SparkApplication) apiv1.ResourceRequirements {
 
 	var cpuQuantity resource.Quantity
 	if app.Spec.Driver.CoreLimit != nil || common.CheckSparkConf(app.Spec.SparkConf, common.SparkDriverCoreLimitKey) {
+		coreLimit := app.Spec.SparkConf[common.SparkDriverCoreLimitKey]
 		if app.Spec.Driver.CoreLimit != nil {
-			cpuQuantity = resource.MustParse(*app.Spec.Driver.CoreLimit)
-		} else {
-			cpuQuantity = resource.MustParse(app.Spec.SparkConf[common.SparkDriverCoreLimitKey])
+			coreLimit = *app.Spec.Driver.CoreLimit
+		}
+		if cpuQuantity, err = resource.ParseQuantity(coreLimit); err != nil {
+			return driverPodResourceRequirement, fmt.Errorf("invalid driver core limit %q: %w", coreLimit, err)
 		}
 
 		driverPodResourceRequirement.Limits = apiv1.ResourceList{
@@ -672,23 +554,21 @@ func handleResources(app *v1beta2.SparkApplication) apiv1.ResourceRequirements {
 	//Cores OR Cores Request - https://github.com/GoogleCloudPlatform/spark-on-k8s-operator/issues/581
 	//spark.kubernetes.driver.request.cores takes precedence over spark.driver.cores for specifying the driver pod cpu request if set.
 	//Priority sequence is - if value supplied in app spec directly, if not, then if supplied in sparkConf or default
+	//Setting default value as cores or coreLimit is not passed
+	coreRequest := "1"
 	if app.Spec.Driver.CoreRequest != nil {
-		cpuQuantity = resource.MustParse(*app.Spec.Driver.CoreRequest)
-		cpuValExists = true
+		coreRequest = *app.Spec.Driver.CoreRequest
 	} else if common.CheckSparkConf(app.Spec.SparkConf, common.SparkDriverCoreRequestKey) {
-		cpuQuantity = resource.MustParse(app.Spec.SparkConf[common.SparkDriverCoreRequestKey])
-		cpuValExists = true
+		coreRequest = app.Spec.SparkConf[common.SparkDriverCoreRequestKey]
 	} else if app.Spec.Driver.Cores != nil {
-		cpuQuantity = resource.MustParse(fmt.Sprint(*app.Spec.Driver.Cores))
-		cpuValExists = true
+		coreRequest = fmt.Sprint(*app.Spec.Driver.Cores)
 	} else if common.CheckSparkConf(app.Spec.SparkConf, SparkDriverCores) {
-		cpuQuantity = resource.MustParse(app.Spec.SparkConf[SparkDriverCores])
-		cpuValExists = true
-	} else {
-		//Setting default value as cores or coreLimit is not passed
-		cpuValExists = true
-		cpuQuantity = resource.MustParse("1")
+		coreRequest = app.Spec.SparkConf[SparkDriverCores]
+	}
+	if cpuQuantity, err = resource.ParseQuantity(coreRequest); err != nil {
+		return driverPodResourceRequirement, fmt.Errorf("invalid driver core request %q: %w", coreRequest, err)
 	}
+	cpuValExists = true
 
 	if cpuValExists && memoryValExists {
 		driverPodResourceRequirement.Requests = apiv1.ResourceList{
@@ -706,5 +586,5 @@ func handleResources(app *v1beta2.SparkApplication) apiv1.ResourceRequirements {
 		}
 	}
 
-	return driverPodResourceRequirement
+	return driverPodResourceRequirement, nil
 }
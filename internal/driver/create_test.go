@@ -4,6 +4,7 @@ import (
 	"context"
 	"nativesubmit/common"
 	"testing"
+	"time"
 
 	v1beta2 "github.com/kubeflow/spark-operator/api/v1beta2"
 	"github.com/stretchr/testify/assert"
@@ -11,7 +12,9 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 func TestCreate(t *testing.T) {
@@ -130,7 +133,7 @@ func TestCreate(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			client := fake.NewClientBuilder().WithScheme(scheme).Build()
-			err := Create(tt.app, tt.serviceLabels, tt.driverConfigMapName, client, tt.appSpecVolumeMounts, tt.appSpecVolumes)
+			err := Create(context.TODO(), tt.app, tt.serviceLabels, tt.driverConfigMapName, client, tt.appSpecVolumeMounts, tt.appSpecVolumes)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -170,6 +173,100 @@ func TestCreate(t *testing.T) {
 	}
 }
 
+func TestCreateOrUpdateReplacesDriverPodOfPreviousSubmission(t *testing.T) {
+	tests := []struct {
+		name                 string
+		existingSubmissionID string
+		existingFinalizers   []string
+		wantOperation        controllerutil.OperationResult
+		wantSubmissionID     string
+	}{
+		{
+			name:                 "pod of the same submission is reused",
+			existingSubmissionID: "submission-2",
+			wantOperation:        controllerutil.OperationResultNone,
+			wantSubmissionID:     "submission-2",
+		},
+		{
+			name:                 "pod of a previous submission is replaced",
+			existingSubmissionID: "submission-1",
+			wantOperation:        controllerutil.OperationResultCreated,
+			wantSubmissionID:     "submission-2",
+		},
+		{
+			name:                 "terminating pod of a previous submission is waited for",
+			existingSubmissionID: "submission-1",
+			existingFinalizers:   []string{"example.com/block"},
+			wantOperation:        controllerutil.OperationResultCreated,
+			wantSubmissionID:     "submission-2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			existingPod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test-app-driver",
+					Namespace:  "default",
+					UID:        "old-pod",
+					Labels:     map[string]string{SubmissionIDLabel: tt.existingSubmissionID},
+					Finalizers: tt.existingFinalizers,
+				},
+			}
+			cl := fake.NewClientBuilder().WithObjects(existingPod).Build()
+			if len(tt.existingFinalizers) > 0 {
+				// Stands in for the kubelet finishing the termination of the old pod
+				go func() {
+					time.Sleep(100 * time.Millisecond)
+					terminatingPod := &corev1.Pod{}
+					_ = cl.Get(context.TODO(), ctrlClient.ObjectKeyFromObject(existingPod), terminatingPod)
+					terminatingPod.Finalizers = nil
+					_ = cl.Update(context.TODO(), terminatingPod)
+				}()
+			}
+
+			driverPod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-app-driver",
+					Namespace: "default",
+					Labels:    map[string]string{SubmissionIDLabel: "submission-2"},
+				},
+			}
+			operationResult, err := CreateOrUpdate(context.TODO(), driverPod, cl, common.SubmitOptions{DriverPodDeletionGracePeriodSeconds: int64ptr(0)})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantOperation, operationResult)
+
+			currentPod := &corev1.Pod{}
+			assert.NoError(t, cl.Get(context.TODO(), ctrlClient.ObjectKeyFromObject(driverPod), currentPod))
+			assert.Equal(t, tt.wantSubmissionID, currentPod.Labels[SubmissionIDLabel])
+		})
+	}
+}
+
+func TestCreateOrUpdateHonoursCancellationWhileWaitingForDeletion(t *testing.T) {
+	existingPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-app-driver",
+			Namespace:  "default",
+			Labels:     map[string]string{SubmissionIDLabel: "submission-1"},
+			Finalizers: []string{"example.com/block"},
+		},
+	}
+	cl := fake.NewClientBuilder().WithObjects(existingPod).Build()
+	ctx, cancel := context.WithTimeout(context.TODO(), 100*time.Millisecond)
+	defer cancel()
+
+	driverPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-app-driver",
+			Namespace: "default",
+			Labels:    map[string]string{SubmissionIDLabel: "submission-2"},
+		},
+	}
+	_, err := CreateOrUpdate(ctx, driverPod, cl, common.SubmitOptions{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
 func int32ptr(i int32) *int32 {
 	return &i
 }
@@ -181,3 +278,34 @@ func int64ptr(i int64) *int64 {
 func stringptr(s string) *string {
 	return &s
 }
+
+func TestLoadPodFromTemplateReportsTempDirFailure(t *testing.T) {
+	t.Setenv("TMPDIR", "/nonexistent/native-submit")
+	_, err := loadPodFromTemplate(context.TODO(), "https://example.com/driver-template.yaml", "", nil)
+	assert.ErrorContains(t, err, "failed to create temporary directory")
+}
+
+func TestCreateDriverPodContainerSpecRejectsInvalidPorts(t *testing.T) {
+	for _, portKey := range []string{common.SparkDriverPort, SparkBlockManagerPort, SparkDriverBlockManagerPort} {
+		app := &v1beta2.SparkApplication{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"},
+			Spec: v1beta2.SparkApplicationSpec{
+				SparkConf: map[string]string{portKey: "http"},
+			},
+		}
+		_, _, err := CreateDriverPodContainerSpec(app)
+		assert.ErrorContains(t, err, "not parseable", portKey)
+	}
+}
+
+func TestCreateDriverPodContainerSpecOmitsRandomPorts(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"},
+		Spec: v1beta2.SparkApplicationSpec{
+			SparkConf: map[string]string{common.SparkDriverPort: "0", SparkDriverBlockManagerPort: "0"},
+		},
+	}
+	container, _, err := CreateDriverPodContainerSpec(app)
+	assert.NoError(t, err)
+	assert.Equal(t, []corev1.ContainerPort{{ContainerPort: UiPort, Name: UiPortName, Protocol: Protocol}}, container.Ports)
+}
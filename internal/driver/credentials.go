@@ -0,0 +1,118 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+	"nativesubmit/common"
+	"nativesubmit/features"
+	"os"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// kubernetesCredential is one credential of the driver's Kubernetes client, shipped under secretKey of the Secret
+type kubernetesCredential struct {
+	secretKey      string
+	fileConfKey    string
+	mountedConfKey string
+}
+
+// kubernetesCredentials are the credentials Spark's DriverKubernetesCredentialsFeatureStep ships, with the Secret keys
+// it uses. The OAuth token can also be given inline with spark.kubernetes.authenticate.driver.oauthToken.
+var kubernetesCredentials = []kubernetesCredential{
+	{secretKey: "oauth-token", fileConfKey: OAuthTokenConfFile, mountedConfKey: OAuthTokenMountedConfFile},
+	{secretKey: "client-key", fileConfKey: ClientKeyFile, mountedConfKey: ClientKeyMountedFile},
+	{secretKey: "client-cert", fileConfKey: ClientCertFile, mountedConfKey: ClientCertMountedFile},
+	{secretKey: "ca-cert", fileConfKey: CaCertFile, mountedConfKey: CaCertMountedFile},
+}
+
+// kubernetesCredentialsStep ships the Kubernetes credentials of the driver, read from files on the submission host or
+// given inline, in a Secret mounted in the driver container, like Spark's DriverKubernetesCredentialsFeatureStep
+type kubernetesCredentialsStep struct{}
+
+func (kubernetesCredentialsStep) Name() string {
+	return "kubernetes-credentials"
+}
+
+func (kubernetesCredentialsStep) ConfigurePod(ctx context.Context, conf *features.DriverConf, pod *features.SparkPod) error {
+	if len(getShippedKubernetesCredentials(conf)) == 0 {
+		return nil
+	}
+	pod.Pod.Spec.Volumes = append(pod.Pod.Spec.Volumes, apiv1.Volume{
+		Name:         KubernetesCredentials,
+		VolumeSource: apiv1.VolumeSource{Secret: &apiv1.SecretVolumeSource{SecretName: getKubernetesCredentialsSecretName(conf)}},
+	})
+	pod.Container.VolumeMounts = append(pod.Container.VolumeMounts, apiv1.VolumeMount{
+		Name:      KubernetesCredentials,
+		MountPath: KubernetesCredentialsVolumeMountPath,
+		ReadOnly:  true,
+	})
+	return nil
+}
+
+// SparkProperties points the file properties of the shipped credentials, and the mounted ones the driver reads, at the
+// files of the mounted Secret
+func (kubernetesCredentialsStep) SparkProperties(ctx context.Context, conf *features.DriverConf) (map[string]string, error) {
+	properties := make(map[string]string)
+	for _, credential := range getShippedKubernetesCredentials(conf) {
+		mountedPath := KubernetesCredentialsVolumeMountPath + ForwardSlash + credential.secretKey
+		if conf.App.Spec.SparkConf[credential.fileConfKey] != "" {
+			properties[credential.fileConfKey] = mountedPath
+		}
+		properties[credential.mountedConfKey] = mountedPath
+	}
+	return properties, nil
+}
+
+// AdditionalResources reads the credentials into the Secret mounted by the driver pod. A dry run does not read them and
+// redacts them.
+func (kubernetesCredentialsStep) AdditionalResources(ctx context.Context, conf *features.DriverConf) ([]ctrlClient.Object, error) {
+	credentials := getShippedKubernetesCredentials(conf)
+	if len(credentials) == 0 {
+		return nil, nil
+	}
+	sparkConf := conf.App.Spec.SparkConf
+	data := make(map[string][]byte)
+	for _, credential := range credentials {
+		if conf.DryRun {
+			data[credential.secretKey] = []byte(common.RedactedValue)
+			continue
+		}
+		if credential.fileConfKey == OAuthTokenConfFile && sparkConf[OAuthToken] != "" {
+			data[credential.secretKey] = []byte(sparkConf[OAuthToken])
+			continue
+		}
+		content, err := os.ReadFile(sparkConf[credential.fileConfKey])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the file %s of %s: %w", sparkConf[credential.fileConfKey], credential.fileConfKey, err)
+		}
+		data[credential.secretKey] = content
+	}
+	return []ctrlClient.Object{&apiv1.Secret{
+		TypeMeta:   metav1.TypeMeta{APIVersion: ApiVersionV1, Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Name: getKubernetesCredentialsSecretName(conf)},
+		Type:       apiv1.SecretTypeOpaque,
+		Data:       data,
+	}}, nil
+}
+
+// getShippedKubernetesCredentials Helper func to get the credentials set in sparkConf. A credential already mounted in
+// the driver pod, through its mounted property, is not shipped.
+func getShippedKubernetesCredentials(conf *features.DriverConf) []kubernetesCredential {
+	sparkConf := conf.App.Spec.SparkConf
+	var credentials []kubernetesCredential
+	for _, credential := range kubernetesCredentials {
+		configured := sparkConf[credential.fileConfKey] != "" || (credential.fileConfKey == OAuthTokenConfFile && sparkConf[OAuthToken] != "")
+		if configured && sparkConf[credential.mountedConfKey] == "" {
+			credentials = append(credentials, credential)
+		}
+	}
+	return credentials
+}
+
+// getKubernetesCredentialsSecretName Helper func to get the name of the Secret the credentials are shipped in
+func getKubernetesCredentialsSecretName(conf *features.DriverConf) string {
+	return common.GetDriverPodName(conf.App) + KubernetesCredentialsSecretExtension
+}
@@ -0,0 +1,103 @@
+package driver
+
+import (
+	"context"
+	"nativesubmit/common"
+	"nativesubmit/features"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestKubernetesCredentialsStep(t *testing.T) {
+	dir := t.TempDir()
+	caCertPath := filepath.Join(dir, "ca.crt")
+	assert.NoError(t, os.WriteFile(caCertPath, []byte("ca certificate"), 0o600))
+	tokenPath := filepath.Join(dir, "token")
+	assert.NoError(t, os.WriteFile(tokenPath, []byte("file token"), 0o600))
+
+	tests := []struct {
+		name           string
+		sparkConf      map[string]string
+		dryRun         bool
+		wantErr        string
+		wantData       map[string][]byte
+		wantProperties map[string]string
+	}{
+		{
+			name:      "no credentials",
+			sparkConf: map[string]string{},
+		},
+		{
+			name:      "credential files",
+			sparkConf: map[string]string{CaCertFile: caCertPath, OAuthTokenConfFile: tokenPath},
+			wantData:  map[string][]byte{"ca-cert": []byte("ca certificate"), "oauth-token": []byte("file token")},
+			wantProperties: map[string]string{
+				CaCertFile:                "/mnt/secrets/spark-kubernetes-credentials/ca-cert",
+				CaCertMountedFile:         "/mnt/secrets/spark-kubernetes-credentials/ca-cert",
+				OAuthTokenConfFile:        "/mnt/secrets/spark-kubernetes-credentials/oauth-token",
+				OAuthTokenMountedConfFile: "/mnt/secrets/spark-kubernetes-credentials/oauth-token",
+			},
+		},
+		{
+			name:      "inline oauth token",
+			sparkConf: map[string]string{OAuthToken: "inline token"},
+			wantData:  map[string][]byte{"oauth-token": []byte("inline token")},
+			wantProperties: map[string]string{
+				OAuthTokenMountedConfFile: "/mnt/secrets/spark-kubernetes-credentials/oauth-token",
+			},
+		},
+		{
+			name:      "dry run",
+			sparkConf: map[string]string{ClientKeyFile: filepath.Join(dir, "client.key"), OAuthToken: "inline token"},
+			dryRun:    true,
+			wantData:  map[string][]byte{"client-key": []byte(common.RedactedValue), "oauth-token": []byte(common.RedactedValue)},
+			wantProperties: map[string]string{
+				ClientKeyFile:             "/mnt/secrets/spark-kubernetes-credentials/client-key",
+				ClientKeyMountedFile:      "/mnt/secrets/spark-kubernetes-credentials/client-key",
+				OAuthTokenMountedConfFile: "/mnt/secrets/spark-kubernetes-credentials/oauth-token",
+			},
+		},
+		{
+			name:      "credentials already mounted",
+			sparkConf: map[string]string{CaCertFile: caCertPath, CaCertMountedFile: "/var/run/secrets/ca.crt"},
+		},
+		{
+			name:      "missing credential file",
+			sparkConf: map[string]string{ClientKeyFile: filepath.Join(dir, "client.key")},
+			wantErr:   "failed to read the file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newSchedulingTestApp()
+			app.Spec.SparkConf = tt.sparkConf
+			pod, contributions, err := BuildWithFeatureSteps(context.TODO(), &features.DriverConf{App: app, ConfigMapName: "test-config-map", DryRun: tt.dryRun}, nil)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assertPodSelfConsistent(t, pod)
+			if tt.wantData == nil {
+				assert.Nil(t, volume(pod, KubernetesCredentials))
+				assert.Empty(t, contributions.AdditionalResources)
+				assert.Empty(t, contributions.SparkProperties)
+				return
+			}
+
+			assert.Contains(t, pod.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{Name: KubernetesCredentials, MountPath: KubernetesCredentialsVolumeMountPath, ReadOnly: true})
+			assert.Equal(t, tt.wantProperties, contributions.SparkProperties)
+			if assert.Len(t, contributions.AdditionalResources, 1) {
+				credentialsSecret := contributions.AdditionalResources[0].(*corev1.Secret)
+				assert.Equal(t, "test-spark-app-driver-kubernetes-credentials", credentialsSecret.Name)
+				assert.Equal(t, tt.wantData, credentialsSecret.Data)
+				assertVolumeSourceExists(t, pod, contributions.AdditionalResources)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+	"nativesubmit/common"
+	"nativesubmit/features"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// kerberosStep sets up Kerberos for the driver like Spark's KerberosConfDriverFeatureStep: it mounts krb5.conf from a
+// ConfigMap, the delegation tokens of an existing Secret, and a keytab shipped from the submission host as a Secret
+type kerberosStep struct{}
+
+func (kerberosStep) Name() string {
+	return "kerberos"
+}
+
+func (kerberosStep) ConfigurePod(ctx context.Context, conf *features.DriverConf, pod *features.SparkPod) error {
+	sparkConf := conf.App.Spec.SparkConf
+	if krb5ConfigMapName := getKrb5ConfigMapName(conf); krb5ConfigMapName != "" {
+		pod.Pod.Spec.Volumes = append(pod.Pod.Spec.Volumes, apiv1.Volume{
+			Name: KerberosFileVolume,
+			VolumeSource: apiv1.VolumeSource{
+				ConfigMap: &apiv1.ConfigMapVolumeSource{
+					LocalObjectReference: apiv1.LocalObjectReference{Name: krb5ConfigMapName},
+					Items:                []apiv1.KeyToPath{{Key: KerberosFileName, Path: KerberosFileName}},
+				},
+			},
+		})
+		pod.Container.VolumeMounts = append(pod.Container.VolumeMounts, apiv1.VolumeMount{
+			Name:      KerberosFileVolume,
+			MountPath: KerberosFileDirectoryPath + ForwardSlash + KerberosFileName,
+			SubPath:   KerberosFileName,
+		})
+	}
+
+	// Handling https://spark.apache.org/docs/latest/security.html#long-running-applications
+	if tokenSecretName := sparkConf[KerberosTokenSecretName]; tokenSecretName != "" {
+		pod.Pod.Spec.Volumes = append(pod.Pod.Spec.Volumes, apiv1.Volume{
+			Name:         KerberosTokenSecretVolume,
+			VolumeSource: apiv1.VolumeSource{Secret: &apiv1.SecretVolumeSource{SecretName: tokenSecretName}},
+		})
+		pod.Container.VolumeMounts = append(pod.Container.VolumeMounts, apiv1.VolumeMount{
+			Name:      KerberosTokenSecretVolume,
+			MountPath: strings.TrimSuffix(KerberosHadoopSecretFilePath, ForwardSlash),
+		})
+		pod.Container.Env = append(pod.Container.Env, apiv1.EnvVar{
+			Name:  KerberosHadoopSecretFilePathKey,
+			Value: KerberosHadoopSecretFilePath + sparkConf[KerberosTokenSecretItemKey],
+		})
+	}
+
+	if _, upload := getLocalKeytab(conf); upload {
+		pod.Pod.Spec.Volumes = append(pod.Pod.Spec.Volumes, apiv1.Volume{
+			Name:         KerberosKeytabVolume,
+			VolumeSource: apiv1.VolumeSource{Secret: &apiv1.SecretVolumeSource{SecretName: getKeytabSecretName(conf)}},
+		})
+		pod.Container.VolumeMounts = append(pod.Container.VolumeMounts, apiv1.VolumeMount{
+			Name:      KerberosKeytabVolume,
+			MountPath: KerberosKeytabMountPath,
+			ReadOnly:  true,
+		})
+	}
+	return nil
+}
+
+// SparkProperties points spark.kerberos.keytab at the keytab mounted in the driver container
+func (kerberosStep) SparkProperties(ctx context.Context, conf *features.DriverConf) (map[string]string, error) {
+	keytabPath, upload := getLocalKeytab(conf)
+	if !upload {
+		return nil, nil
+	}
+	return map[string]string{KerberosKeytab: KerberosKeytabMountPath + ForwardSlash + filepath.Base(keytabPath)}, nil
+}
+
+// AdditionalResources reads the local krb5.conf and keytab of the submission host into a ConfigMap and a Secret. A dry
+// run does not read the keytab and redacts it.
+func (kerberosStep) AdditionalResources(ctx context.Context, conf *features.DriverConf) ([]ctrlClient.Object, error) {
+	app := conf.App
+	var resources []ctrlClient.Object
+	if krb5Path := app.Spec.SparkConf[KerberosPath]; krb5Path != "" && app.Spec.SparkConf[KerberosConfigMapName] == "" {
+		krb5Conf, err := os.ReadFile(krb5Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the krb5 file %s of %s: %w", krb5Path, KerberosPath, err)
+		}
+		resources = append(resources, &apiv1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{APIVersion: ApiVersionV1, Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Name: getKrb5ConfigMapName(conf)},
+			Data:       map[string]string{KerberosFileName: string(krb5Conf)},
+		})
+	}
+	if keytabPath, upload := getLocalKeytab(conf); upload {
+		keytab := []byte(common.RedactedValue)
+		if !conf.DryRun {
+			var err error
+			if keytab, err = os.ReadFile(keytabPath); err != nil {
+				return nil, fmt.Errorf("failed to read the keytab %s of %s: %w", keytabPath, KerberosKeytab, err)
+			}
+		}
+		resources = append(resources, &apiv1.Secret{
+			TypeMeta:   metav1.TypeMeta{APIVersion: ApiVersionV1, Kind: "Secret"},
+			ObjectMeta: metav1.ObjectMeta{Name: getKeytabSecretName(conf)},
+			Type:       apiv1.SecretTypeOpaque,
+			Data:       map[string][]byte{filepath.Base(keytabPath): keytab},
+		})
+	}
+	return resources, nil
+}
+
+// getKrb5ConfigMapName Helper func to get the ConfigMap krb5.conf is mounted from, the existing one of krb5.configMapName
+// or the one created from krb5.path
+func getKrb5ConfigMapName(conf *features.DriverConf) string {
+	sparkConf := conf.App.Spec.SparkConf
+	if configMapName := sparkConf[KerberosConfigMapName]; configMapName != "" {
+		return configMapName
+	}
+	if sparkConf[KerberosPath] != "" {
+		return common.GetDriverPodName(conf.App) + KerberosFileConfigMapExtension
+	}
+	return ""
+}
+
+// getLocalKeytab Helper func to get the path of the keytab on the submission host, for a plain path or a file URI. Any
+// other keytab, such as one with the local scheme that is already in the image, is left to Spark.
+func getLocalKeytab(conf *features.DriverConf) (string, bool) {
+	keytab := conf.App.Spec.SparkConf[KerberosKeytab]
+	if keytab == "" {
+		return "", false
+	}
+	keytabURI, err := url.Parse(keytab)
+	if err != nil || keytabURI.Scheme == "" {
+		return keytab, true
+	}
+	if keytabURI.Scheme == "file" {
+		return keytabURI.Path, true
+	}
+	return "", false
+}
+
+// getKeytabSecretName Helper func to get the name of the Secret a local keytab is shipped in
+func getKeytabSecretName(conf *features.DriverConf) string {
+	return common.GetDriverPodName(conf.App) + KerberosKeytabSecretExtension
+}
@@ -0,0 +1,185 @@
+package driver
+
+import (
+	"context"
+	"nativesubmit/common"
+	"nativesubmit/features"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestKerberosStep(t *testing.T) {
+	dir := t.TempDir()
+	krb5Path := filepath.Join(dir, "realm.conf")
+	assert.NoError(t, os.WriteFile(krb5Path, []byte("[libdefaults]\n default_realm = EXAMPLE.COM\n"), 0o600))
+	keytabPath := filepath.Join(dir, "spark.keytab")
+	assert.NoError(t, os.WriteFile(keytabPath, []byte{0x05, 0x02}, 0o600))
+
+	tests := []struct {
+		name      string
+		sparkConf map[string]string
+		dryRun    bool
+		wantErr   string
+		check     func(t *testing.T, pod *corev1.Pod, contributions *features.Contributions)
+	}{
+		{
+			name:      "disabled",
+			sparkConf: map[string]string{},
+			check: func(t *testing.T, pod *corev1.Pod, contributions *features.Contributions) {
+				assert.NotContains(t, volumeNames(pod), KerberosFileVolume)
+				assert.Empty(t, contributions.AdditionalResources)
+			},
+		},
+		{
+			name:      "krb5 file from a local path",
+			sparkConf: map[string]string{KerberosPath: krb5Path},
+			check: func(t *testing.T, pod *corev1.Pod, contributions *features.Contributions) {
+				assert.Contains(t, pod.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{Name: KerberosFileVolume, MountPath: "/etc/krb5.conf", SubPath: KerberosFileName})
+				if assert.Len(t, contributions.AdditionalResources, 1) {
+					krb5ConfigMap := contributions.AdditionalResources[0].(*corev1.ConfigMap)
+					assert.Equal(t, "test-spark-app-driver"+KerberosFileConfigMapExtension, krb5ConfigMap.Name)
+					assert.Equal(t, "default", krb5ConfigMap.Namespace)
+					assert.Contains(t, krb5ConfigMap.Data[KerberosFileName], "EXAMPLE.COM")
+					assertVolumeSourceExists(t, pod, contributions.AdditionalResources)
+				}
+			},
+		},
+		{
+			name:      "existing krb5 configmap",
+			sparkConf: map[string]string{KerberosConfigMapName: "krb5"},
+			check: func(t *testing.T, pod *corev1.Pod, contributions *features.Contributions) {
+				assert.Equal(t, "krb5", volume(pod, KerberosFileVolume).ConfigMap.Name)
+				assert.Empty(t, contributions.AdditionalResources)
+			},
+		},
+		{
+			name:      "delegation token secret",
+			sparkConf: map[string]string{KerberosTokenSecretName: "hadoop-tokens", KerberosTokenSecretItemKey: "hadoop.token"},
+			check: func(t *testing.T, pod *corev1.Pod, contributions *features.Contributions) {
+				assert.Equal(t, "hadoop-tokens", volume(pod, KerberosTokenSecretVolume).Secret.SecretName)
+				assert.Contains(t, pod.Spec.Containers[0].Env, corev1.EnvVar{Name: KerberosHadoopSecretFilePathKey, Value: "/mnt/secrets/hadoop-credentials/hadoop.token"})
+				assert.Contains(t, pod.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{Name: KerberosTokenSecretVolume, MountPath: "/mnt/secrets/hadoop-credentials"})
+			},
+		},
+		{
+			name:      "local keytab",
+			sparkConf: map[string]string{KerberosKeytab: "file://" + keytabPath, KerberosPrincipal: "spark@EXAMPLE.COM"},
+			check: func(t *testing.T, pod *corev1.Pod, contributions *features.Contributions) {
+				assert.Equal(t, KerberosKeytabMountPath+"/spark.keytab", contributions.SparkProperties[KerberosKeytab])
+				assert.Contains(t, pod.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{Name: KerberosKeytabVolume, MountPath: KerberosKeytabMountPath, ReadOnly: true})
+				if assert.Len(t, contributions.AdditionalResources, 1) {
+					keytabSecret := contributions.AdditionalResources[0].(*corev1.Secret)
+					assert.Equal(t, []byte{0x05, 0x02}, keytabSecret.Data["spark.keytab"])
+					assertVolumeSourceExists(t, pod, contributions.AdditionalResources)
+				}
+			},
+		},
+		{
+			name:      "dry run keytab",
+			sparkConf: map[string]string{KerberosKeytab: filepath.Join(dir, "missing.keytab"), KerberosPrincipal: "spark@EXAMPLE.COM"},
+			dryRun:    true,
+			check: func(t *testing.T, pod *corev1.Pod, contributions *features.Contributions) {
+				if assert.Len(t, contributions.AdditionalResources, 1) {
+					keytabSecret := contributions.AdditionalResources[0].(*corev1.Secret)
+					assert.Equal(t, map[string][]byte{"missing.keytab": []byte(common.RedactedValue)}, keytabSecret.Data)
+				}
+			},
+		},
+		{
+			name:      "keytab in the image",
+			sparkConf: map[string]string{KerberosKeytab: "local:///etc/security/spark.keytab", KerberosPrincipal: "spark@EXAMPLE.COM"},
+			check: func(t *testing.T, pod *corev1.Pod, contributions *features.Contributions) {
+				assert.NotContains(t, volumeNames(pod), KerberosKeytabVolume)
+				assert.NotContains(t, contributions.SparkProperties, KerberosKeytab)
+				assert.Empty(t, contributions.AdditionalResources)
+			},
+		},
+		{
+			name:      "missing krb5 file",
+			sparkConf: map[string]string{KerberosPath: filepath.Join(dir, "missing.conf")},
+			wantErr:   "failed to read the krb5 file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newSchedulingTestApp()
+			app.Spec.SparkConf = tt.sparkConf
+			pod, contributions, err := BuildWithFeatureSteps(context.TODO(), &features.DriverConf{App: app, ConfigMapName: "test-config-map", DryRun: tt.dryRun}, nil)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assertPodSelfConsistent(t, pod)
+			tt.check(t, pod, contributions)
+		})
+	}
+}
+
+// assertPodSelfConsistent Helper func to check every volume mount of the pod refers to a volume of the pod, and a sub
+// path of a ConfigMap volume with items to one of its items
+func assertPodSelfConsistent(t *testing.T, pod *corev1.Pod) {
+	for _, container := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+		for _, volumeMount := range container.VolumeMounts {
+			mounted := volume(pod, volumeMount.Name)
+			if !assert.NotNil(t, mounted, "volume of mount %s of container %s", volumeMount.Name, container.Name) {
+				continue
+			}
+			if volumeMount.SubPath != "" && mounted.ConfigMap != nil && len(mounted.ConfigMap.Items) > 0 {
+				var paths []string
+				for _, item := range mounted.ConfigMap.Items {
+					paths = append(paths, item.Path)
+				}
+				assert.Contains(t, paths, strings.SplitN(volumeMount.SubPath, "/", 2)[0], "sub path of mount %s", volumeMount.Name)
+			}
+		}
+	}
+}
+
+// assertVolumeSourceExists Helper func to check every resource shipped with the pod is mounted by it, and that the
+// items of a ConfigMap volume are keys of the ConfigMap
+func assertVolumeSourceExists(t *testing.T, pod *corev1.Pod, resources []ctrlClient.Object) {
+	for _, resource := range resources {
+		referenced := false
+		for _, podVolume := range pod.Spec.Volumes {
+			switch r := resource.(type) {
+			case *corev1.ConfigMap:
+				if podVolume.ConfigMap != nil && podVolume.ConfigMap.Name == r.Name {
+					referenced = true
+					for _, item := range podVolume.ConfigMap.Items {
+						assert.Contains(t, r.Data, item.Key)
+					}
+				}
+			case *corev1.Secret:
+				referenced = referenced || (podVolume.Secret != nil && podVolume.Secret.SecretName == r.Name)
+			}
+		}
+		assert.True(t, referenced, "%s is mounted by the pod", resource.GetName())
+	}
+}
+
+// volume Helper func to get a volume of the pod by name
+func volume(pod *corev1.Pod, name string) *corev1.Volume {
+	for i := range pod.Spec.Volumes {
+		if pod.Spec.Volumes[i].Name == name {
+			return &pod.Spec.Volumes[i]
+		}
+	}
+	return nil
+}
+
+// volumeNames Helper func to list the volume names of the pod
+func volumeNames(pod *corev1.Pod) []string {
+	var names []string
+	for _, podVolume := range pod.Spec.Volumes {
+		names = append(names, podVolume.Name)
+	}
+	return names
+}
@@ -0,0 +1,426 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+	"nativesubmit/common"
+	"nativesubmit/features"
+	"nativesubmit/internal/executor"
+	"strings"
+
+	"github.com/kubeflow/spark-operator/api/v1beta2"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// podStep adapts a function configuring the driver pod to a feature step
+type podStep struct {
+	features.NoopStep
+	name      string
+	configure func(conf *features.DriverConf, pod *features.SparkPod) error
+}
+
+func (s podStep) Name() string {
+	return s.name
+}
+
+func (s podStep) ConfigurePod(ctx context.Context, conf *features.DriverConf, pod *features.SparkPod) error {
+	return s.configure(conf, pod)
+}
+
+// BuiltinSteps returns the steps building the driver pod of a Spark Application, in the order they run
+func BuiltinSteps() []features.Step {
+	return []features.Step{
+		podStep{name: "basic-driver", configure: configureBasicDriver},
+		podStep{name: "node-selector", configure: configureNodeSelector},
+		podStep{name: "scheduling", configure: configureScheduling},
+		podStep{name: "image-pull-secrets", configure: configureImagePullSecrets},
+		podStep{name: "security-context", configure: configureSecurityContext},
+		podStep{name: "tolerations", configure: configureTolerations},
+		podStep{name: "spark-conf-volume", configure: configureSparkConfVolume},
+		podStep{name: "local-dirs", configure: configureLocalDirs},
+		podStep{name: "secrets", configure: configureSecrets},
+		podStep{name: "config-maps", configure: configureConfigMaps},
+		podStep{name: "hadoop-conf", configure: configureHadoopConf},
+		kerberosStep{},
+		kubernetesCredentialsStep{},
+		podStep{name: "sidecars", configure: configureSideCars},
+		podStep{name: "init-containers", configure: configureInitContainers},
+		podStep{name: "networking", configure: configureNetworking},
+	}
+}
+
+// configureBasicDriver sets the metadata of the driver pod, its pod level defaults and the Spark driver container
+func configureBasicDriver(conf *features.DriverConf, pod *features.SparkPod) error {
+	app := conf.App
+	// Spark Application Driver Pod schema populating with specific values/data
+	var podObjectMetadata metav1.ObjectMeta
+	//Driver Pod Name
+	podObjectMetadata.Name = common.GetDriverPodName(app)
+	//Driver pod Namespace
+	podObjectMetadata.Namespace = common.GetAppNamespace(app)
+	//Driver Pod labels
+	podObjectMetadata.Labels = conf.Labels
+	//Driver pod annotations
+	if app.Spec.Driver.Annotations != nil {
+		podObjectMetadata.Annotations = app.Spec.Driver.Annotations
+	} else {
+		annotations := make(map[string]string)
+		for sparkConfKey, sparkConfValue := range app.Spec.SparkConf {
+			if strings.Contains(sparkConfKey, "spark.kubernetes.driver.annotation.") {
+				lastDotIndex := strings.LastIndex(sparkConfKey, DotSeparator)
+				annotationKey := sparkConfKey[lastDotIndex+1:]
+				annotations[annotationKey] = sparkConfValue
+			}
+		}
+		if len(annotations) > 0 {
+			podObjectMetadata.Annotations = annotations
+		}
+	}
+	//Driver Pod Owner Reference
+	podObjectMetadata.OwnerReferences = []metav1.OwnerReference{*common.GetOwnerReference(app)}
+	pod.Pod.ObjectMeta = podObjectMetadata
+
+	driverPodSpec := &pod.Pod.Spec
+	//Driver pod DNS policy
+	driverPodSpec.DNSPolicy = SparkDriverDNSPolicy
+
+	//Driver pod enable service link
+	driverPodSpec.EnableServiceLinks = common.BoolPointer(true)
+
+	//RestartPolicy
+	driverPodSpec.RestartPolicy = DriverPodRestartPolicyNever
+
+	//Service Account
+	if app.Spec.Driver.ServiceAccount != nil {
+		driverPodSpec.ServiceAccountName = *app.Spec.Driver.ServiceAccount
+	} else if common.CheckSparkConf(app.Spec.SparkConf, "spark.kubernetes.authenticate.driver.serviceAccountName") {
+		driverPodSpec.ServiceAccountName = app.Spec.SparkConf["spark.kubernetes.authenticate.driver.serviceAccountName"]
+	}
+	//Termination grace period
+	if app.Spec.Driver.TerminationGracePeriodSeconds != nil {
+		driverPodSpec.TerminationGracePeriodSeconds = app.Spec.Driver.TerminationGracePeriodSeconds
+	} else {
+		driverPodSpec.TerminationGracePeriodSeconds = common.Int64Pointer(DefaultTerminationGracePeriodSeconds)
+	}
+
+	driverPodContainerSpec, _, containerSpecErr := CreateDriverPodContainerSpec(app)
+	if containerSpecErr != nil {
+		return fmt.Errorf("failed to create the driver container spec for the driver pod %s in namespace %s: %w", common.GetDriverPodName(app), app.Namespace, containerSpecErr)
+	}
+	pod.Container = &driverPodContainerSpec
+	return nil
+}
+
+// configureNodeSelector sets the node selector of the driver pod
+func configureNodeSelector(conf *features.DriverConf, pod *features.SparkPod) error {
+	app := conf.App
+	//Priority wise, values mentioned in Spec i.e. app.Spec.NodeSelector or/and app.Spec.Driver.NodeSelector will be higher than
+	// ones specified in sparkConf
+	if app.Spec.NodeSelector != nil {
+		pod.Pod.Spec.NodeSelector = app.Spec.NodeSelector
+	} else if app.Spec.Driver.NodeSelector != nil {
+		pod.Pod.Spec.NodeSelector = app.Spec.Driver.NodeSelector
+	} else {
+		nodeSelectorList := make(map[string]string)
+		//Driver pod node selector
+		for sparkConfKey, sparkConfValue := range app.Spec.SparkConf {
+			if strings.Contains(sparkConfKey, SparkNodeSelectorPrefix) {
+				lastDotIndex := strings.LastIndex(sparkConfKey, DotSeparator)
+				driverPodNodeSelectorKey := sparkConfKey[lastDotIndex+1:]
+				nodeSelectorList[driverPodNodeSelectorKey] = sparkConfValue
+			}
+		}
+		for sparkConfKey, sparkConfValue := range app.Spec.SparkConf {
+			if strings.Contains(sparkConfKey, SparkDriverPodNodeSelectorPrefix) {
+				lastDotIndex := strings.LastIndex(sparkConfKey, DotSeparator)
+				driverPodNodeSelectorKey := sparkConfKey[lastDotIndex+1:]
+				nodeSelectorList[driverPodNodeSelectorKey] = sparkConfValue
+			}
+		}
+		if len(nodeSelectorList) > 0 {
+			pod.Pod.Spec.NodeSelector = nodeSelectorList
+		}
+	}
+	return nil
+}
+
+// configureScheduling sets the scheduler, affinity, priority class, topology spread constraints and scheduling gates
+// of the driver pod. Priority wise, the fields of the driver spec come first, then the ones of the driver spec
+// template, then the sparkConf properties; the pod template file, if any, provides the value when none of them does.
+func configureScheduling(conf *features.DriverConf, pod *features.SparkPod) error {
+	app := conf.App
+	driverPodSpec := &pod.Pod.Spec
+	var templateSpec apiv1.PodSpec
+	if app.Spec.Driver.Template != nil {
+		templateSpec = app.Spec.Driver.Template.Spec
+	}
+
+	//Pod Scheduler Name
+	driverPodSchedulerName, driverPodSchedulerValueExists := app.Spec.SparkConf[common.SparkDriverSchedulerNameKey]
+	podSchedulerName, podSchedulerValueExists := app.Spec.SparkConf[common.SparkSchedulerNameKey]
+	if app.Spec.Driver.SchedulerName != nil {
+		driverPodSpec.SchedulerName = *app.Spec.Driver.SchedulerName
+	} else if templateSpec.SchedulerName != "" {
+		driverPodSpec.SchedulerName = templateSpec.SchedulerName
+	} else if driverPodSchedulerValueExists {
+		driverPodSpec.SchedulerName = driverPodSchedulerName
+	} else if podSchedulerValueExists {
+		driverPodSpec.SchedulerName = podSchedulerName
+	}
+
+	//Affinity
+	if app.Spec.Driver.Affinity != nil {
+		driverPodSpec.Affinity = app.Spec.Driver.Affinity
+	} else if templateSpec.Affinity != nil {
+		driverPodSpec.Affinity = templateSpec.Affinity
+	}
+
+	//Priority class
+	if app.Spec.Driver.PriorityClassName != nil {
+		driverPodSpec.PriorityClassName = *app.Spec.Driver.PriorityClassName
+	} else if templateSpec.PriorityClassName != "" {
+		driverPodSpec.PriorityClassName = templateSpec.PriorityClassName
+	}
+	if driverPodSpec.PriorityClassName != "" {
+		// The priority class resolves the priority, a priority copied from a pod template would be rejected
+		driverPodSpec.Priority = nil
+	}
+
+	//Topology spread constraints
+	if templateSpec.TopologySpreadConstraints != nil {
+		driverPodSpec.TopologySpreadConstraints = templateSpec.TopologySpreadConstraints
+	}
+
+	//Scheduling gates
+	if templateSpec.SchedulingGates != nil {
+		driverPodSpec.SchedulingGates = templateSpec.SchedulingGates
+	}
+	return nil
+}
+
+// configureImagePullSecrets sets the image pull secrets of the driver pod
+func configureImagePullSecrets(conf *features.DriverConf, pod *features.SparkPod) error {
+	app := conf.App
+	var imagePullSecrets []string
+	if app.Spec.ImagePullSecrets != nil {
+		imagePullSecrets = app.Spec.ImagePullSecrets
+	} else if common.CheckSparkConf(app.Spec.SparkConf, common.SparkImagePullSecretKey) {
+		imagePullSecretList := app.Spec.SparkConf[common.SparkImagePullSecretKey]
+		imagePullSecrets = strings.Split(imagePullSecretList, ",")
+	}
+	var imagePullSecretsList []apiv1.LocalObjectReference
+	for _, secretName := range imagePullSecrets {
+		var imagePullSecret apiv1.LocalObjectReference
+		imagePullSecret.Name = secretName
+		imagePullSecretsList = append(imagePullSecretsList, imagePullSecret)
+	}
+	pod.Pod.Spec.ImagePullSecrets = imagePullSecretsList
+	return nil
+}
+
+// configureSecurityContext sets the pod security context of the driver pod
+func configureSecurityContext(conf *features.DriverConf, pod *features.SparkPod) error {
+	app := conf.App
+	if app.Spec.Driver.SecurityContext != nil {
+		if app.Spec.Driver.SecurityContext.RunAsUser != nil || app.Spec.Driver.SecurityContext.RunAsNonRoot != nil {
+			var podSecurityContext apiv1.PodSecurityContext
+			if app.Spec.Driver.SecurityContext.RunAsUser != nil {
+				podSecurityContext.RunAsUser = app.Spec.Driver.SecurityContext.RunAsUser
+				podSecurityContext.FSGroup = app.Spec.Driver.SecurityContext.RunAsUser
+				podSecurityContext.SupplementalGroups = SupplementalGroups(*app.Spec.Driver.SecurityContext.RunAsUser)
+			}
+			if app.Spec.Driver.SecurityContext.RunAsNonRoot != nil {
+				podSecurityContext.RunAsNonRoot = app.Spec.Driver.SecurityContext.RunAsNonRoot
+			}
+
+			pod.Pod.Spec.SecurityContext = &podSecurityContext
+		}
+	} else {
+		pod.Pod.Spec.SecurityContext = &apiv1.PodSecurityContext{
+			RunAsUser: common.Int64Pointer(DriverPodSecurityContextID),
+			FSGroup:   common.Int64Pointer(DriverPodSecurityContextID),
+			//Run as non-root
+			RunAsNonRoot:       common.BoolPointer(true),
+			SupplementalGroups: SupplementalGroups(DriverPodSecurityContextID),
+		}
+	}
+	return nil
+}
+
+// configureTolerations sets the tolerations of the driver pod, defaulting to the ones for unready and unreachable nodes
+func configureTolerations(conf *features.DriverConf, pod *features.SparkPod) error {
+	app := conf.App
+	if app.Spec.Driver.Tolerations != nil {
+		pod.Pod.Spec.Tolerations = app.Spec.Driver.Tolerations
+		return nil
+	}
+	//Assigning default toleration
+	pod.Pod.Spec.Tolerations = []apiv1.Toleration{
+		{
+			Effect:            TolerationEffect,
+			Key:               NodeNotReady,
+			Operator:          Operator,
+			TolerationSeconds: common.Int64Pointer(DefaultTolerationSeconds),
+		},
+		{
+			Effect:            TolerationEffect,
+			Key:               NodeNotReachable,
+			Operator:          Operator,
+			TolerationSeconds: common.Int64Pointer(DefaultTolerationSeconds),
+		},
+	}
+	return nil
+}
+
+// configureSparkConfVolume adds the volume of the Spark Application ConfigMap, which the driver container mounts.
+// The executor pod template, when there is one, is mounted along with spark.properties.
+func configureSparkConfVolume(conf *features.DriverConf, pod *features.SparkPod) error {
+	// spark-conf-volume-driver addition
+	sparkConfVolume := apiv1.Volume{
+		Name: SparkConfVolumeDriver,
+		VolumeSource: apiv1.VolumeSource{
+			ConfigMap: &apiv1.ConfigMapVolumeSource{
+				DefaultMode: Int32Pointer(420),
+				Items: []apiv1.KeyToPath{
+					{
+						Key:  SparkEnvScriptFileName,
+						Mode: Int32Pointer(420),
+						Path: SparkEnvScriptFileName,
+					},
+					{
+						Key:  SparkPropertiesFileName,
+						Mode: Int32Pointer(420),
+						Path: SparkPropertiesFileName,
+					},
+				},
+				LocalObjectReference: apiv1.LocalObjectReference{Name: conf.ConfigMapName},
+			},
+		},
+	}
+	if executor.HasPodTemplate(conf.App) {
+		sparkConfVolume.ConfigMap.Items = append(sparkConfVolume.ConfigMap.Items, apiv1.KeyToPath{
+			Key:  executor.PodTemplateFileName,
+			Mode: Int32Pointer(420),
+			Path: executor.PodTemplateFileName,
+		})
+	}
+	if sparkConfigMap := conf.App.Spec.SparkConfigMap; sparkConfigMap != nil {
+		// The files of the Spark ConfigMap are projected next to the generated ones, which win on a name clash. Spark
+		// copies the files of SPARK_CONF_DIR, which points at this volume, to the executors.
+		generated := sparkConfVolume.ConfigMap
+		sparkConfVolume.VolumeSource = apiv1.VolumeSource{
+			Projected: &apiv1.ProjectedVolumeSource{
+				DefaultMode: generated.DefaultMode,
+				Sources: []apiv1.VolumeProjection{
+					{ConfigMap: &apiv1.ConfigMapProjection{LocalObjectReference: apiv1.LocalObjectReference{Name: *sparkConfigMap}}},
+					{ConfigMap: &apiv1.ConfigMapProjection{LocalObjectReference: generated.LocalObjectReference, Items: generated.Items}},
+				},
+			},
+		}
+	}
+	pod.Pod.Spec.Volumes = append(pod.Pod.Spec.Volumes, sparkConfVolume)
+	return nil
+}
+
+// configureLocalDirs mounts the Spark local directories in the driver container
+func configureLocalDirs(conf *features.DriverConf, pod *features.SparkPod) error {
+	app := conf.App
+	resolvedLocalDirs, _ := processSparkConfEnv(app, nil)
+	localDirFeatureSetupError := handleLocalDirsFeatureStep(app, resolvedLocalDirs, &pod.Pod.Spec.Volumes, &pod.Container.VolumeMounts, &pod.Container.Env, conf.AppSpecVolumeMounts, conf.AppSpecVolumes)
+	if localDirFeatureSetupError != nil {
+		return fmt.Errorf("failed to setup local directory for the driver pod %s in namespace %s: %v", common.GetDriverPodName(app), app.Namespace, localDirFeatureSetupError)
+	}
+	return nil
+}
+
+// configureSecrets mounts the driver secrets of the app spec and of sparkConf in the driver container
+func configureSecrets(conf *features.DriverConf, pod *features.SparkPod) error {
+	app := conf.App
+	volumeExtension := "-volume"
+	for _, secret := range app.Spec.Driver.Secrets {
+		pod.Pod.Spec.Volumes, *pod.Container = addSecret(secret, volumeExtension, pod.Pod.Spec.Volumes, *pod.Container)
+	}
+	//Populating secrets passed in sparkConf
+	for sparkConfKey, sparkConfValue := range app.Spec.SparkConf {
+		if strings.Contains(sparkConfKey, common.SparkDriverSecretKeyPrefix) {
+			lastDotIndex := strings.LastIndex(sparkConfKey, DotSeparator)
+			driverPodSecretKey := sparkConfKey[lastDotIndex+1:]
+			var secret v1beta2.SecretInfo
+			secret.Name = driverPodSecretKey
+			secret.Path = sparkConfValue
+			pod.Pod.Spec.Volumes, *pod.Container = addSecret(secret, volumeExtension, pod.Pod.Spec.Volumes, *pod.Container)
+		}
+	}
+	return nil
+}
+
+// configureConfigMaps adds a volume for every config map of the driver spec and mounts it in the driver container
+func configureConfigMaps(conf *features.DriverConf, pod *features.SparkPod) error {
+	for _, configMap := range conf.App.Spec.Driver.ConfigMaps {
+		volumeName := configMap.Name + ConfigMapVolumeExtension
+		pod.Pod.Spec.Volumes = append(pod.Pod.Spec.Volumes, apiv1.Volume{
+			Name: volumeName,
+			VolumeSource: apiv1.VolumeSource{
+				ConfigMap: &apiv1.ConfigMapVolumeSource{LocalObjectReference: apiv1.LocalObjectReference{Name: configMap.Name}},
+			},
+		})
+		pod.Container.VolumeMounts = append(pod.Container.VolumeMounts, apiv1.VolumeMount{Name: volumeName, MountPath: configMap.Path})
+	}
+	return nil
+}
+
+// configureHadoopConf mounts the Hadoop ConfigMap of the app spec in the driver container and points HADOOP_CONF_DIR
+// at it. Spark mounts it in the executors, through spark.kubernetes.hadoop.configMapName.
+func configureHadoopConf(conf *features.DriverConf, pod *features.SparkPod) error {
+	hadoopConfigMap := conf.App.Spec.HadoopConfigMap
+	if hadoopConfigMap == nil {
+		return nil
+	}
+	pod.Pod.Spec.Volumes = append(pod.Pod.Spec.Volumes, apiv1.Volume{
+		Name: HadoopConfVolume,
+		VolumeSource: apiv1.VolumeSource{
+			ConfigMap: &apiv1.ConfigMapVolumeSource{LocalObjectReference: apiv1.LocalObjectReference{Name: *hadoopConfigMap}},
+		},
+	})
+	pod.Container.VolumeMounts = append(pod.Container.VolumeMounts, apiv1.VolumeMount{Name: HadoopConfVolume, MountPath: HadoopConfDirPath})
+	pod.Container.Env = append(pod.Container.Env, apiv1.EnvVar{Name: HadoopConfDir, Value: HadoopConfDirPath})
+	return nil
+}
+
+// configureSideCars adds the sidecar containers of the driver
+func configureSideCars(conf *features.DriverConf, pod *features.SparkPod) error {
+	pod.Pod.Spec.Containers = handleSideCars(conf.App, pod.Pod.Spec.Containers, conf.AppSpecVolumes)
+	return nil
+}
+
+// configureInitContainers sets the init containers of the driver
+func configureInitContainers(conf *features.DriverConf, pod *features.SparkPod) error {
+	if conf.App.Spec.Driver.InitContainers != nil {
+		pod.Pod.Spec.InitContainers = conf.App.Spec.Driver.InitContainers
+	}
+	return nil
+}
+
+// configureNetworking sets the host network, DNS config, host aliases and process namespace sharing of the driver pod.
+// A driver pod on the host network keeps resolving cluster DNS names through the ClusterFirstWithHostNet DNS policy.
+func configureNetworking(conf *features.DriverConf, pod *features.SparkPod) error {
+	driver := conf.App.Spec.Driver
+	driverPodSpec := &pod.Pod.Spec
+	if driver.HostNetwork != nil {
+		driverPodSpec.HostNetwork = *driver.HostNetwork
+	}
+	if driverPodSpec.HostNetwork {
+		driverPodSpec.DNSPolicy = SparkDriverHostNetworkDNSPolicy
+	}
+	if driver.DNSConfig != nil {
+		driverPodSpec.DNSConfig = driver.DNSConfig
+	}
+	if driver.HostAliases != nil {
+		driverPodSpec.HostAliases = driver.HostAliases
+	}
+	if driver.ShareProcessNamespace != nil {
+		driverPodSpec.ShareProcessNamespace = driver.ShareProcessNamespace
+	}
+	return nil
+}
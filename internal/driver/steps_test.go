@@ -0,0 +1,427 @@
+package driver
+
+import (
+	"context"
+	"nativesubmit/common"
+	"nativesubmit/features"
+	"slices"
+	"testing"
+
+	v1beta2 "github.com/kubeflow/spark-operator/api/v1beta2"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// newSchedulingTestApp Helper func to build a Spark Application for the scheduling tests
+func newSchedulingTestApp() *v1beta2.SparkApplication {
+	return &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-spark-app", Namespace: "default"},
+		Spec: v1beta2.SparkApplicationSpec{
+			Type:      v1beta2.SparkApplicationTypeScala,
+			Mode:      v1beta2.DeployModeCluster,
+			Image:     stringptr("spark:3.5.0"),
+			SparkConf: map[string]string{},
+		},
+	}
+}
+
+func TestCreateSchedulingFields(t *testing.T) {
+	onDemandAffinity := &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+		NodeSelectorTerms: []corev1.NodeSelectorTerm{{MatchExpressions: []corev1.NodeSelectorRequirement{
+			{Key: "karpenter.sh/capacity-type", Operator: corev1.NodeSelectorOpNotIn, Values: []string{"spot"}},
+		}}},
+	}}}
+	zoneSpread := []corev1.TopologySpreadConstraint{{
+		MaxSkew:           1,
+		TopologyKey:       "topology.kubernetes.io/zone",
+		WhenUnsatisfiable: corev1.ScheduleAnyway,
+		LabelSelector:     &metav1.LabelSelector{MatchLabels: map[string]string{"spark-role": "driver"}},
+	}}
+
+	tests := []struct {
+		name   string
+		mutate func(app *v1beta2.SparkApplication)
+		check  func(t *testing.T, spec corev1.PodSpec)
+	}{
+		{
+			name:   "defaults",
+			mutate: func(app *v1beta2.SparkApplication) {},
+			check: func(t *testing.T, spec corev1.PodSpec) {
+				assert.Empty(t, spec.SchedulerName)
+				assert.Nil(t, spec.Affinity)
+				assert.Empty(t, spec.PriorityClassName)
+				assert.Nil(t, spec.TopologySpreadConstraints)
+				assert.Nil(t, spec.SchedulingGates)
+			},
+		},
+		{
+			name: "driver spec fields",
+			mutate: func(app *v1beta2.SparkApplication) {
+				app.Spec.Driver.SchedulerName = stringptr("volcano")
+				app.Spec.Driver.Affinity = onDemandAffinity
+				app.Spec.Driver.PriorityClassName = stringptr("spark-drivers")
+				app.Spec.SparkConf[common.SparkDriverSchedulerNameKey] = "yunikorn"
+			},
+			check: func(t *testing.T, spec corev1.PodSpec) {
+				assert.Equal(t, "volcano", spec.SchedulerName)
+				assert.Equal(t, onDemandAffinity, spec.Affinity)
+				assert.Equal(t, "spark-drivers", spec.PriorityClassName)
+			},
+		},
+		{
+			name: "driver spec template",
+			mutate: func(app *v1beta2.SparkApplication) {
+				app.Spec.Driver.Template = &corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+					SchedulerName:             "volcano",
+					Affinity:                  onDemandAffinity,
+					PriorityClassName:         "spark-drivers",
+					TopologySpreadConstraints: zoneSpread,
+					SchedulingGates:           []corev1.PodSchedulingGate{{Name: "example.com/quota"}},
+				}}
+				app.Spec.SparkConf[common.SparkDriverSchedulerNameKey] = "yunikorn"
+			},
+			check: func(t *testing.T, spec corev1.PodSpec) {
+				assert.Equal(t, "volcano", spec.SchedulerName)
+				assert.Equal(t, onDemandAffinity, spec.Affinity)
+				assert.Equal(t, "spark-drivers", spec.PriorityClassName)
+				assert.Equal(t, zoneSpread, spec.TopologySpreadConstraints)
+				assert.Equal(t, []corev1.PodSchedulingGate{{Name: "example.com/quota"}}, spec.SchedulingGates)
+			},
+		},
+		{
+			name: "sparkConf properties",
+			mutate: func(app *v1beta2.SparkApplication) {
+				app.Spec.SparkConf[common.SparkSchedulerNameKey] = "yunikorn"
+				app.Spec.SparkConf["spark.kubernetes.driver.priorityClassName"] = "spark-drivers"
+				app.Spec.SparkConf["spark.kubernetes.driver.schedulingGates"] = "example.com/quota"
+			},
+			check: func(t *testing.T, spec corev1.PodSpec) {
+				assert.Equal(t, "yunikorn", spec.SchedulerName)
+				// Spark has no properties for the priority class or the scheduling gates of the driver pod
+				assert.Empty(t, spec.PriorityClassName)
+				assert.Nil(t, spec.SchedulingGates)
+			},
+		},
+		{
+			name: "driver scheduler name over the application one",
+			mutate: func(app *v1beta2.SparkApplication) {
+				app.Spec.SparkConf[common.SparkSchedulerNameKey] = "yunikorn"
+				app.Spec.SparkConf[common.SparkDriverSchedulerNameKey] = "volcano"
+			},
+			check: func(t *testing.T, spec corev1.PodSpec) {
+				assert.Equal(t, "volcano", spec.SchedulerName)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newSchedulingTestApp()
+			tt.mutate(app)
+			client := fake.NewClientBuilder().Build()
+			assert.NoError(t, Create(context.TODO(), app, map[string]string{"spark-role": "driver"}, "test-config-map", client, nil, nil))
+
+			pod := &corev1.Pod{}
+			assert.NoError(t, client.Get(context.TODO(), ctrlClient.ObjectKey{Namespace: "default", Name: common.GetDriverPodName(app)}, pod))
+			tt.check(t, pod.Spec)
+		})
+	}
+}
+
+func TestCreateNetworkingFields(t *testing.T) {
+	dnsConfig := &corev1.PodDNSConfig{Nameservers: []string{"10.0.0.10"}, Searches: []string{"spark.svc.cluster.local"}}
+	hostAliases := []corev1.HostAlias{{IP: "10.0.0.20", Hostnames: []string{"metastore.internal"}}}
+
+	tests := []struct {
+		name   string
+		mutate func(app *v1beta2.SparkApplication)
+		check  func(t *testing.T, spec corev1.PodSpec)
+	}{
+		{
+			name:   "defaults",
+			mutate: func(app *v1beta2.SparkApplication) {},
+			check: func(t *testing.T, spec corev1.PodSpec) {
+				assert.False(t, spec.HostNetwork)
+				assert.Equal(t, corev1.DNSClusterFirst, spec.DNSPolicy)
+				assert.Nil(t, spec.DNSConfig)
+				assert.Nil(t, spec.HostAliases)
+				assert.Nil(t, spec.ShareProcessNamespace)
+			},
+		},
+		{
+			name: "driver spec fields",
+			mutate: func(app *v1beta2.SparkApplication) {
+				app.Spec.Driver.HostNetwork = common.BoolPointer(true)
+				app.Spec.Driver.DNSConfig = dnsConfig
+				app.Spec.Driver.HostAliases = hostAliases
+				app.Spec.Driver.ShareProcessNamespace = common.BoolPointer(true)
+			},
+			check: func(t *testing.T, spec corev1.PodSpec) {
+				assert.True(t, spec.HostNetwork)
+				assert.Equal(t, corev1.DNSClusterFirstWithHostNet, spec.DNSPolicy)
+				assert.Equal(t, dnsConfig, spec.DNSConfig)
+				assert.Equal(t, hostAliases, spec.HostAliases)
+				assert.Equal(t, common.BoolPointer(true), spec.ShareProcessNamespace)
+			},
+		},
+		{
+			name: "host network disabled",
+			mutate: func(app *v1beta2.SparkApplication) {
+				app.Spec.Driver.HostNetwork = common.BoolPointer(false)
+			},
+			check: func(t *testing.T, spec corev1.PodSpec) {
+				assert.False(t, spec.HostNetwork)
+				assert.Equal(t, corev1.DNSClusterFirst, spec.DNSPolicy)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newSchedulingTestApp()
+			tt.mutate(app)
+			client := fake.NewClientBuilder().Build()
+			assert.NoError(t, Create(context.TODO(), app, map[string]string{"spark-role": "driver"}, "test-config-map", client, nil, nil))
+
+			pod := &corev1.Pod{}
+			assert.NoError(t, client.Get(context.TODO(), ctrlClient.ObjectKey{Namespace: "default", Name: common.GetDriverPodName(app)}, pod))
+			tt.check(t, pod.Spec)
+		})
+	}
+}
+
+func TestCreateConfigMaps(t *testing.T) {
+	app := newSchedulingTestApp()
+	app.Spec.Volumes = []corev1.Volume{{Name: "data", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}}
+	app.Spec.Driver.ConfigMaps = []v1beta2.NamePath{{Name: "log4j", Path: "/etc/log4j"}, {Name: "metrics", Path: "/etc/metrics"}}
+	app.Spec.Driver.Sidecars = []corev1.Container{
+		{Name: "log-shipper", Image: "fluent-bit", VolumeMounts: []corev1.VolumeMount{
+			{Name: "log4j-vol"},
+			{Name: "data", MountPath: "/data", ReadOnly: true},
+			{Name: "unknown", MountPath: "/unknown"},
+		}},
+		{Name: "proxy", Image: "envoy", VolumeMounts: []corev1.VolumeMount{{Name: "metrics-vol", MountPath: "/etc/envoy/metrics"}}},
+		{Name: "idle", Image: "busybox"},
+	}
+	client := fake.NewClientBuilder().Build()
+	assert.NoError(t, Create(context.TODO(), app, map[string]string{"spark-role": "driver"}, "test-config-map", client, nil, app.Spec.Volumes))
+
+	pod := &corev1.Pod{}
+	assert.NoError(t, client.Get(context.TODO(), ctrlClient.ObjectKey{Namespace: "default", Name: common.GetDriverPodName(app)}, pod))
+	volumes := make(map[string]corev1.Volume)
+	for _, volume := range pod.Spec.Volumes {
+		volumes[volume.Name] = volume
+	}
+	for _, name := range []string{"log4j", "metrics"} {
+		if assert.Contains(t, volumes, name+"-vol") {
+			assert.Equal(t, name, volumes[name+"-vol"].ConfigMap.Name)
+		}
+	}
+
+	containers := make(map[string]corev1.Container)
+	for _, container := range pod.Spec.Containers {
+		containers[container.Name] = container
+	}
+	assert.Subset(t, containers[common.SparkDriverContainerName].VolumeMounts, []corev1.VolumeMount{
+		{Name: "log4j-vol", MountPath: "/etc/log4j"},
+		{Name: "metrics-vol", MountPath: "/etc/metrics"},
+	})
+	assert.Equal(t, []corev1.VolumeMount{
+		{Name: "log4j-vol", MountPath: "/etc/log4j"},
+		{Name: "data", MountPath: "/data", ReadOnly: true},
+	}, containers["log-shipper"].VolumeMounts)
+	assert.Equal(t, []corev1.VolumeMount{{Name: "metrics-vol", MountPath: "/etc/envoy/metrics"}}, containers["proxy"].VolumeMounts)
+	assert.Empty(t, containers["idle"].VolumeMounts)
+}
+
+func TestCreateHadoopAndSparkConfigMaps(t *testing.T) {
+	app := newSchedulingTestApp()
+	app.Spec.SparkConfigMap = stringptr("spark-conf")
+	app.Spec.HadoopConfigMap = stringptr("hadoop-conf")
+	client := fake.NewClientBuilder().Build()
+	assert.NoError(t, Create(context.TODO(), app, map[string]string{"spark-role": "driver"}, "test-config-map", client, nil, nil))
+
+	pod := &corev1.Pod{}
+	assert.NoError(t, client.Get(context.TODO(), ctrlClient.ObjectKey{Namespace: "default", Name: common.GetDriverPodName(app)}, pod))
+	volumes := make(map[string]corev1.Volume)
+	for _, volume := range pod.Spec.Volumes {
+		volumes[volume.Name] = volume
+	}
+	if assert.NotNil(t, volumes[SparkConfVolumeDriver].Projected) {
+		sources := volumes[SparkConfVolumeDriver].Projected.Sources
+		if assert.Len(t, sources, 2) {
+			// The generated files come last, so they win over the ones of the Spark ConfigMap
+			assert.Equal(t, "spark-conf", sources[0].ConfigMap.Name)
+			assert.Empty(t, sources[0].ConfigMap.Items)
+			assert.Equal(t, "test-config-map", sources[1].ConfigMap.Name)
+			assert.NotEmpty(t, sources[1].ConfigMap.Items)
+		}
+	}
+	if assert.NotNil(t, volumes[HadoopConfVolume].ConfigMap) {
+		assert.Equal(t, "hadoop-conf", volumes[HadoopConfVolume].ConfigMap.Name)
+	}
+
+	driverContainer := pod.Spec.Containers[0]
+	assert.Contains(t, driverContainer.VolumeMounts, corev1.VolumeMount{Name: HadoopConfVolume, MountPath: HadoopConfDirPath})
+	assert.Contains(t, driverContainer.VolumeMounts, corev1.VolumeMount{Name: SparkConfVolumeDriver, MountPath: SparkConfVolumeDriverMountPath})
+	assert.Contains(t, driverContainer.Env, corev1.EnvVar{Name: HadoopConfDir, Value: HadoopConfDirPath})
+	sparkConfDirs := 0
+	for _, envVar := range driverContainer.Env {
+		if envVar.Name == common.SparkConfDirEnvVar {
+			sparkConfDirs++
+			assert.Equal(t, SparkConfVolumeDriverMountPath, envVar.Value)
+		}
+	}
+	assert.Equal(t, 1, sparkConfDirs)
+}
+
+func TestCreateSecretKeyRefEnvVars(t *testing.T) {
+	app := newSchedulingTestApp()
+	app.Spec.SparkConf[common.SparkDriverSecretKeyRefKeyPrefix+"DB_PASSWORD"] = "db-credentials:password"
+	app.Spec.SparkConf[common.SparkDriverSecretKeyRefKeyPrefix+"API_TOKEN"] = "old-token:token"
+	app.Spec.Driver.EnvSecretKeyRefs = map[string]v1beta2.NameKey{"API_TOKEN": {Name: "api-token", Key: "token"}}
+	app.Spec.Driver.EnvFrom = []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "db-env"}}}}
+	client := fake.NewClientBuilder().Build()
+	assert.NoError(t, Create(context.TODO(), app, map[string]string{"spark-role": "driver"}, "test-config-map", client, nil, nil))
+
+	pod := &corev1.Pod{}
+	assert.NoError(t, client.Get(context.TODO(), ctrlClient.ObjectKey{Namespace: "default", Name: common.GetDriverPodName(app)}, pod))
+	driverContainer := pod.Spec.Containers[0]
+	secretKeyRefs := make(map[string]*corev1.SecretKeySelector)
+	for _, envVar := range driverContainer.Env {
+		if envVar.Name == "API_TOKEN" || envVar.Name == "DB_PASSWORD" {
+			assert.Empty(t, envVar.Value)
+			if assert.NotNil(t, envVar.ValueFrom) {
+				secretKeyRefs[envVar.Name] = envVar.ValueFrom.SecretKeyRef
+			}
+		}
+	}
+	assert.Equal(t, map[string]*corev1.SecretKeySelector{
+		"API_TOKEN":   {LocalObjectReference: corev1.LocalObjectReference{Name: "api-token"}, Key: "token"},
+		"DB_PASSWORD": {LocalObjectReference: corev1.LocalObjectReference{Name: "db-credentials"}, Key: "password"},
+	}, secretKeyRefs)
+	assert.Equal(t, app.Spec.Driver.EnvFrom, driverContainer.EnvFrom)
+}
+
+func TestCreateDriverEnvPrecedence(t *testing.T) {
+	podIP := &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "status.podIP"}}
+	app := newSchedulingTestApp()
+	app.Spec.SparkConf["spark.kubernetes.driverEnv.LOG_LEVEL"] = "debug"
+	app.Spec.SparkConf["spark.kubernetes.driverEnv.REGION"] = "eu-west-1"
+	app.Spec.SparkConf["spark.kubernetes.driverEnv.TOKEN"] = "plain"
+	app.Spec.SparkConf[common.SparkDriverSecretKeyRefKeyPrefix+"TOKEN"] = "tokens:conf"
+	app.Spec.SparkConf["spark.kubernetes.driverEnv.HOST_IP"] = "127.0.0.1"
+	app.Spec.Driver.EnvVars = map[string]string{"LOG_LEVEL": "info", "PASSWORD": "plain"}
+	app.Spec.Driver.EnvSecretKeyRefs = map[string]v1beta2.NameKey{"PASSWORD": {Name: "db", Key: "password"}}
+	app.Spec.Driver.Env = []corev1.EnvVar{
+		{Name: "HOST_IP", ValueFrom: podIP},
+		{Name: "ENDPOINT", Value: "http://$(HOST_IP):8080"},
+		{Name: SparkLocalDir, Value: "/data/a,/data/b"},
+	}
+	client := fake.NewClientBuilder().Build()
+	assert.NoError(t, Create(context.TODO(), app, map[string]string{"spark-role": "driver"}, "test-config-map", client, nil, nil))
+
+	pod := &corev1.Pod{}
+	assert.NoError(t, client.Get(context.TODO(), ctrlClient.ObjectKey{Namespace: "default", Name: common.GetDriverPodName(app)}, pod))
+	var userEnvVars []corev1.EnvVar
+	for _, envVar := range pod.Spec.Containers[0].Env {
+		switch envVar.Name {
+		case SparkUser, SparkApplicationID, SparkDriverBindAddress, common.SparkConfDirEnvVar, SparkLocalDir:
+		default:
+			userEnvVars = append(userEnvVars, envVar)
+		}
+	}
+	assert.Equal(t, []corev1.EnvVar{
+		{Name: "LOG_LEVEL", Value: "info"},
+		{Name: "PASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "db"}, Key: "password"}}},
+		{Name: "REGION", Value: "eu-west-1"},
+		{Name: "TOKEN", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "tokens"}, Key: "conf"}}},
+		{Name: "HOST_IP", ValueFrom: podIP},
+		{Name: "ENDPOINT", Value: "http://$(HOST_IP):8080"},
+	}, userEnvVars)
+
+	// The local dirs of the winning SPARK_LOCAL_DIRS are mounted
+	var mountPaths []string
+	for _, volumeMount := range pod.Spec.Containers[0].VolumeMounts {
+		mountPaths = append(mountPaths, volumeMount.MountPath)
+	}
+	assert.Subset(t, mountPaths, []string{"/data/a", "/data/b"})
+}
+
+func TestCreateDriverEnvCollapsesDuplicates(t *testing.T) {
+	app := newSchedulingTestApp()
+	app.Spec.SparkConf["spark.kubernetes.driverEnv.LOG_LEVEL"] = "debug"
+	app.Spec.Driver.Env = []corev1.EnvVar{
+		{Name: "HOST", Value: "localhost"},
+		{Name: "ENDPOINT", Value: "http://$(HOST):8080"},
+		{Name: "HOST", Value: "example.com"},
+		{Name: "LOG_LEVEL", Value: "info"},
+		{Name: common.SparkConfDirEnvVar, Value: "/etc/spark"},
+		{Name: SparkLocalDir, Value: "/data/a"},
+	}
+	client := fake.NewClientBuilder().Build()
+	assert.NoError(t, Create(context.TODO(), app, map[string]string{"spark-role": "driver"}, "test-config-map", client, nil, nil))
+
+	pod := &corev1.Pod{}
+	assert.NoError(t, client.Get(context.TODO(), ctrlClient.ObjectKey{Namespace: "default", Name: common.GetDriverPodName(app)}, pod))
+	envVars := map[string]string{}
+	var envNames []string
+	for _, envVar := range pod.Spec.Containers[0].Env {
+		assert.NotContains(t, envVars, envVar.Name, "duplicate env var %s", envVar.Name)
+		envVars[envVar.Name] = envVar.Value
+		envNames = append(envNames, envVar.Name)
+	}
+	assert.Equal(t, "example.com", envVars["HOST"])
+	assert.Equal(t, "info", envVars["LOG_LEVEL"])
+	// The env vars native submit sets win over the driver spec
+	assert.Equal(t, SparkConfVolumeDriverMountPath, envVars[common.SparkConfDirEnvVar])
+	assert.Equal(t, "/data/a,", envVars[SparkLocalDir])
+	// HOST keeps the position of its first entry, so ENDPOINT can still refer to it
+	assert.Less(t, slices.Index(envNames, "HOST"), slices.Index(envNames, "ENDPOINT"))
+}
+
+func TestCreateDriverEnvKeepsSparkConfDir(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(app *v1beta2.SparkApplication)
+	}{
+		{
+			name: "spark.kubernetes.driverEnv",
+			mutate: func(app *v1beta2.SparkApplication) {
+				app.Spec.SparkConf["spark.kubernetes.driverEnv."+common.SparkConfDirEnvVar] = "/etc/spark"
+			},
+		},
+		{
+			name: "spec.driver.envVars",
+			mutate: func(app *v1beta2.SparkApplication) {
+				app.Spec.Driver.EnvVars = map[string]string{common.SparkConfDirEnvVar: "/etc/spark"}
+			},
+		},
+		{
+			name: "spec.driver.env",
+			mutate: func(app *v1beta2.SparkApplication) {
+				app.Spec.Driver.Env = []corev1.EnvVar{{Name: common.SparkConfDirEnvVar, Value: "/etc/spark"}}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newSchedulingTestApp()
+			tt.mutate(app)
+			pod, _, err := BuildWithFeatureSteps(context.TODO(), &features.DriverConf{App: app, ConfigMapName: "test-config-map"}, nil)
+			assert.NoError(t, err)
+			var sparkConfDirs []corev1.EnvVar
+			for _, envVar := range pod.Spec.Containers[0].Env {
+				if envVar.Name == common.SparkConfDirEnvVar {
+					sparkConfDirs = append(sparkConfDirs, envVar)
+				}
+			}
+			// The driver reads its configuration from the mounted ConfigMap, whatever the app sets
+			assert.Equal(t, []corev1.EnvVar{{Name: common.SparkConfDirEnvVar, Value: SparkConfVolumeDriverMountPath}}, sparkConfDirs)
+		})
+	}
+}
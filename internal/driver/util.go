@@ -1,9 +1,9 @@
 package driver
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"log"
 	"nativesubmit/common"
 	"net/http"
 	"net/url"
@@ -12,9 +12,13 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/go-logr/logr"
 	"github.com/kubeflow/spark-operator/api/v1beta2"
 	apiv1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes/scheme"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type SupplementalGroup []int64
@@ -27,7 +31,7 @@ func Int32Pointer(a int32) *int32 {
 	return &a
 }
 
-func downloadFile(path string, targetDir string, sparkConf map[string]string) (string, error) {
+func downloadFile(ctx context.Context, path string, targetDir string, sparkConf map[string]string) (string, error) {
 	if path == "" {
 		return "", fmt.Errorf("Pod template file's download path is empty")
 	}
@@ -41,18 +45,18 @@ func downloadFile(path string, targetDir string, sparkConf map[string]string) (s
 		return path, nil
 	case "http", "https", "ftp":
 		fname := filepath.Base(uri.Path)
-		localFile, _ := doFetchFile(uri.String(), targetDir, fname, sparkConf)
+		localFile, _ := doFetchFile(ctx, uri.String(), targetDir, fname, sparkConf)
 		return localFile, nil
 	default:
 		fname := filepath.Base(uri.Path)
-		localFile, err := doFetchFile(uri.String(), targetDir, fname, sparkConf)
+		localFile, err := doFetchFile(ctx, uri.String(), targetDir, fname, sparkConf)
 		if err != nil {
 			return "", err
 		}
 		return localFile, nil
 	}
 }
-func doFetchFile(urlStr string, targetDir string, filename string, conf map[string]string) (string, error) {
+func doFetchFile(ctx context.Context, urlStr string, targetDir string, filename string, conf map[string]string) (string, error) {
 	targetFile := filepath.Join(targetDir, filename)
 	parsedURL, err := url.Parse(urlStr)
 	if err != nil {
@@ -71,7 +75,11 @@ func doFetchFile(urlStr string, targetDir string, filename string, conf map[stri
 		client := http.Client{
 			Timeout: fetchTimeoutDuration,
 		}
-		resp, err := client.Get(urlStr)
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
+		if err != nil {
+			return "", err
+		}
+		resp, err := client.Do(request)
 		if err != nil {
 			return "", err
 		}
@@ -109,19 +117,22 @@ func downloadFileFromURL(reader io.Reader, destFile string) (string, error) {
 
 	return copyFile(tempFile.Name(), destFile, true)
 }
-func createTempDir() string {
+func createTempDir() (string, error) {
 	dir, err := os.MkdirTemp("", "spark")
 	if err != nil {
-		log.Fatal(err)
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
 	}
-	return dir
+	return dir, nil
 }
-func loadPodFromTemplate(templateFileName string, containerName string, conf map[string]string) (apiv1.Pod, error) {
+func loadPodFromTemplate(ctx context.Context, templateFileName string, containerName string, conf map[string]string) (apiv1.Pod, error) {
 	var file apiv1.Pod
-	localFile, err := downloadFile(templateFileName, createTempDir(), conf)
+	targetDir, err := createTempDir()
+	if err != nil {
+		return file, fmt.Errorf("encountered exception while preparing to download the pod template file: %w", err)
+	}
+	localFile, err := downloadFile(ctx, templateFileName, targetDir, conf)
 	if err != nil {
-		fmt.Errorf("Encountered exception while attempting to download the pod template file : %v", err)
-		return file, err
+		return file, fmt.Errorf("encountered exception while attempting to download the pod template file: %w", err)
 	} else {
 		data, err := os.ReadFile(localFile)
 		if err != nil {
@@ -134,11 +145,11 @@ func loadPodFromTemplate(templateFileName string, containerName string, conf map
 			return file, err
 		}
 		pod := obj.(*apiv1.Pod)
-		newPod := selectSparkContainer(*pod, containerName)
+		newPod := selectSparkContainer(ctx, *pod, containerName)
 		return newPod, nil
 	}
 }
-func selectSparkContainer(pod apiv1.Pod, containerName string) apiv1.Pod {
+func selectSparkContainer(ctx context.Context, pod apiv1.Pod, containerName string) apiv1.Pod {
 	selectNamedContainer := func(containers []apiv1.Container, name string) (*apiv1.Container, []apiv1.Container, bool) {
 		var rest []apiv1.Container
 		for _, container := range containers {
@@ -147,7 +158,7 @@ func selectSparkContainer(pod apiv1.Pod, containerName string) apiv1.Pod {
 			}
 			rest = append(rest, container)
 		}
-		log.Printf("specified container %s not found on pod template, falling back to taking the first container", name)
+		logr.FromContextOrDiscard(ctx).Info("Specified container not found on pod template, falling back to taking the first container", "container", name)
 		return nil, nil, false
 	}
 
@@ -177,7 +188,7 @@ func selectSparkContainer(pod apiv1.Pod, containerName string) apiv1.Pod {
 	}
 	return apiv1.Pod{}
 }
-func getBlockManagerPort(sparkConfKeyValuePairs map[string]string) int {
+func getBlockManagerPort(sparkConfKeyValuePairs map[string]string) (int, error) {
 	//BlockManager Port
 	blockManagerPortToBeUsed := common.DefaultBlockManagerPort
 	sparkBlockManagerPortSupplied, sparkBlockManagerPortSuppliedValueExists := sparkConfKeyValuePairs[SparkBlockManagerPort]
@@ -185,19 +196,19 @@ func getBlockManagerPort(sparkConfKeyValuePairs map[string]string) int {
 	if sparkDriverBlockManagerPortSuppliedValueExists {
 		blockManagerPortFromConfig, err := strconv.Atoi(sparkDriverBlockManagerPortSupplied)
 		if err != nil {
-			panic("Block Manager Port not parseable to integer value - hence failing the spark submit" + fmt.Sprint(err))
+			return 0, fmt.Errorf("block manager port %q not parseable to integer value - hence failing the spark submit: %w", sparkDriverBlockManagerPortSupplied, err)
 		} else {
 			blockManagerPortToBeUsed = blockManagerPortFromConfig
 		}
 	} else if sparkBlockManagerPortSuppliedValueExists {
 		blockManagerPortFromConfig, err := strconv.Atoi(sparkBlockManagerPortSupplied)
 		if err != nil {
-			panic("Driver Block Manager Port not parseable to integer value - hence failing the spark submit" + fmt.Sprint(err))
+			return 0, fmt.Errorf("driver block manager port %q not parseable to integer value - hence failing the spark submit: %w", sparkBlockManagerPortSupplied, err)
 		} else {
 			blockManagerPortToBeUsed = blockManagerPortFromConfig
 		}
 	}
-	return blockManagerPortToBeUsed
+	return blockManagerPortToBeUsed, nil
 }
 
 func addSecret(secret v1beta2.SecretInfo, volumeExtension string, driverPodVolumes []apiv1.Volume, driverPodContainerSpec apiv1.Container) ([]apiv1.Volume, apiv1.Container) {
@@ -219,3 +230,38 @@ func addSecret(secret v1beta2.SecretInfo, volumeExtension string, driverPodVolum
 	driverPodContainerSpec.VolumeMounts = append(driverPodContainerSpec.VolumeMounts, volumeMount)
 	return driverPodVolumes, driverPodContainerSpec
 }
+
+// deletePodAndWait Helper func to delete a pod and wait until the API server no longer returns it.
+// The wait is bounded by the deletion grace period plus PodDeletionTimeoutPadding, and by ctx.
+func deletePodAndWait(ctx context.Context, pod *apiv1.Pod, kubeClient ctrlClient.Client, gracePeriodSeconds *int64) error {
+	podUID := pod.UID
+	deleteOptions := []ctrlClient.DeleteOption{ctrlClient.Preconditions{UID: &podUID}}
+	gracePeriod := int64(DefaultTerminationGracePeriodSeconds)
+	if pod.Spec.TerminationGracePeriodSeconds != nil {
+		gracePeriod = *pod.Spec.TerminationGracePeriodSeconds
+	}
+	if gracePeriodSeconds != nil {
+		gracePeriod = *gracePeriodSeconds
+		deleteOptions = append(deleteOptions, ctrlClient.GracePeriodSeconds(gracePeriod))
+	}
+	if err := kubeClient.Delete(ctx, pod, deleteOptions...); err != nil && !apiErrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete pod %s in namespace %s: %w", pod.Name, pod.Namespace, err)
+	}
+
+	timeout := time.Duration(gracePeriod)*time.Second + PodDeletionTimeoutPadding
+	waitErr := wait.PollUntilContextTimeout(ctx, PodDeletionPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		currentPod := &apiv1.Pod{}
+		err := kubeClient.Get(ctx, ctrlClient.ObjectKeyFromObject(pod), currentPod)
+		if apiErrors.IsNotFound(err) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return currentPod.UID != podUID, nil
+	})
+	if waitErr != nil {
+		return fmt.Errorf("pod %s in namespace %s did not disappear after deletion: %w", pod.Name, pod.Namespace, waitErr)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package events
+
+const (
+	// Component is the source component of the events emitted by native submit
+	Component = "native-submit"
+
+	// ReasonValidationFailed is emitted when the Spark Application is rejected before anything is created
+	ReasonValidationFailed = "SubmissionValidationFailed"
+	// ReasonSubmissionFailed is emitted when the submission fails before any resource is written
+	ReasonSubmissionFailed = "SubmissionFailed"
+	// ReasonResourcesRendered is emitted once the ConfigMap, Driver Pod and Service are rendered
+	ReasonResourcesRendered = "SubmissionResourcesRendered"
+	// ReasonPreviousSubmissionReplaced is emitted when a restarted application replaces the resources of its previous
+	// submission
+	ReasonPreviousSubmissionReplaced = "PreviousSubmissionReplaced"
+	// ReasonDriverPodTemplateLoaded is emitted when the driver pod is built from a pod template file
+	ReasonDriverPodTemplateLoaded = "DriverPodTemplateLoaded"
+	// ReasonRolledBack is emitted when the resources of a failed submission are deleted again
+	ReasonRolledBack = "SubmissionRolledBack"
+	// ReasonResourcesKept is emitted when the resources of a failed submission are kept for debugging
+	ReasonResourcesKept = "SubmissionResourcesKept"
+
+	// Reasons of the events emitted for each resource, named after the resource and what happened to it
+	ReasonConfigMapCreated   = "DriverConfigMapCreated"
+	ReasonConfigMapUpdated   = "DriverConfigMapUpdated"
+	ReasonConfigMapUnchanged = "DriverConfigMapUnchanged"
+	ReasonConfigMapFailed    = "DriverConfigMapFailed"
+	ReasonDriverPodCreated   = "DriverPodCreated"
+	ReasonDriverPodUpdated   = "DriverPodUpdated"
+	ReasonDriverPodReused    = "DriverPodReused"
+	ReasonDriverPodFailed    = "DriverPodFailed"
+	ReasonServiceCreated     = "DriverServiceCreated"
+	ReasonServiceUpdated     = "DriverServiceUpdated"
+	ReasonServiceUnchanged   = "DriverServiceUnchanged"
+	ReasonServiceFailed      = "DriverServiceFailed"
+
+	// Reasons of the events emitted for each resource contributed by a feature step of the driver pod
+	ReasonAdditionalResourceCreated   = "AdditionalResourceCreated"
+	ReasonAdditionalResourceUpdated   = "AdditionalResourceUpdated"
+	ReasonAdditionalResourceUnchanged = "AdditionalResourceUnchanged"
+	ReasonAdditionalResourceFailed    = "AdditionalResourceFailed"
+)
@@ -0,0 +1,169 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/kubeflow/spark-operator/api/v1beta2"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+const (
+	// createTimeout bounds the API server call of a single event
+	createTimeout = 5 * time.Second
+	// queueLength is the number of events waiting to be written, further events are dropped
+	queueLength = 100
+)
+
+// scheme resolves the kind of the objects events are recorded on, as typed objects often carry no TypeMeta
+var scheme = runtime.NewScheme()
+
+func init() {
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1beta2.AddToScheme(scheme)
+}
+
+// Recorder is a record.EventRecorder that writes core v1 Events through a controller-runtime client. It is used
+// when the host does not supply a recorder, which needs a clientset and a broadcaster to construct. Events are best
+// effort: they are queued and written by a background goroutine, so recording never waits for the API server, and
+// they are dropped when the queue is full.
+type Recorder struct {
+	ctx        context.Context
+	kubeClient ctrlClient.Client
+	logger     logr.Logger
+
+	mu     sync.Mutex
+	closed bool
+	queue  chan *apiv1.Event
+	done   chan struct{}
+}
+
+// NewRecorder returns an event recorder that creates Events through the given client for as long as ctx is not done,
+// and logs the events it drops or fails to create to logger. Close must be called once no more events are recorded.
+func NewRecorder(ctx context.Context, kubeClient ctrlClient.Client, logger logr.Logger) *Recorder {
+	r := &Recorder{
+		ctx:        ctx,
+		kubeClient: kubeClient,
+		logger:     logger,
+		queue:      make(chan *apiv1.Event, queueLength),
+		done:       make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+func (r *Recorder) Event(object runtime.Object, eventtype, reason, message string) {
+	r.record(object, nil, eventtype, reason, message)
+}
+
+func (r *Recorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.record(object, nil, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+func (r *Recorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.record(object, annotations, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// Close stops the recorder from accepting events. The events already queued are still written, Wait blocks until
+// they are.
+func (r *Recorder) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.closed {
+		r.closed = true
+		close(r.queue)
+	}
+}
+
+// Wait blocks until the recorder is closed and its queued events are written, or until ctx is done
+func (r *Recorder) Wait(ctx context.Context) error {
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// record Helper func to queue the Event, events that cannot be queued are logged and otherwise ignored
+func (r *Recorder) record(object runtime.Object, annotations map[string]string, eventtype, reason, message string) {
+	reference, err := objectReference(object)
+	if err != nil {
+		r.logger.Error(err, "Failed to record event", "reason", reason)
+		return
+	}
+	now := metav1.Now()
+	event := &apiv1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%v.%x", reference.Name, now.UnixNano()),
+			Namespace:   reference.Namespace,
+			Annotations: annotations,
+		},
+		InvolvedObject:      *reference,
+		Reason:              reason,
+		Message:             message,
+		Type:                eventtype,
+		Source:              apiv1.EventSource{Component: Component},
+		FirstTimestamp:      now,
+		LastTimestamp:       now,
+		Count:               1,
+		ReportingController: Component,
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		r.logger.Info("Dropped event recorded after the recorder was closed", "reason", reason, "kind", reference.Kind, "name", reference.Name)
+		return
+	}
+	select {
+	case r.queue <- event:
+	default:
+		r.logger.Info("Dropped event as the event queue is full", "reason", reason, "kind", reference.Kind, "name", reference.Name)
+	}
+}
+
+// run Helper func to write the queued events until the recorder is closed, failures are logged and otherwise ignored
+func (r *Recorder) run() {
+	defer close(r.done)
+	for event := range r.queue {
+		if err := r.create(event); err != nil {
+			r.logger.Error(err, "Failed to record event", "reason", event.Reason, "kind", event.InvolvedObject.Kind, "name", event.InvolvedObject.Name)
+		}
+	}
+}
+
+// create Helper func to write one Event, bounded by createTimeout
+func (r *Recorder) create(event *apiv1.Event) error {
+	ctx, cancel := context.WithTimeout(r.ctx, createTimeout)
+	defer cancel()
+	return r.kubeClient.Create(ctx, event)
+}
+
+// objectReference Helper func to build the reference to the object an event is about
+func objectReference(object runtime.Object) (*apiv1.ObjectReference, error) {
+	accessor, err := meta.Accessor(object)
+	if err != nil {
+		return nil, err
+	}
+	gvk, err := apiutil.GVKForObject(object, scheme)
+	if err != nil {
+		return nil, err
+	}
+	return &apiv1.ObjectReference{
+		APIVersion:      gvk.GroupVersion().String(),
+		Kind:            gvk.Kind,
+		Name:            accessor.GetName(),
+		Namespace:       accessor.GetNamespace(),
+		UID:             accessor.GetUID(),
+		ResourceVersion: accessor.GetResourceVersion(),
+	}, nil
+}
@@ -0,0 +1,105 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/kubeflow/spark-operator/api/v1beta2"
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestClientRecorder(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-app",
+			Namespace: "default",
+			UID:       "test-app-uid",
+		},
+	}
+	cl := fake.NewClientBuilder().Build()
+	recorder := NewRecorder(context.TODO(), cl, logr.Discard())
+
+	recorder.Eventf(app, apiv1.EventTypeNormal, ReasonDriverPodCreated, "Created Pod %s/%s", "default", "test-app-driver")
+	recorder.AnnotatedEventf(app, map[string]string{"submission": "1"}, apiv1.EventTypeWarning, ReasonRolledBack, "Rolled back")
+	recorder.Close()
+	assert.NoError(t, recorder.Wait(context.TODO()))
+	// Events recorded after Close are dropped
+	recorder.Event(app, apiv1.EventTypeNormal, ReasonResourcesRendered, "Rendered")
+
+	eventList := &apiv1.EventList{}
+	assert.NoError(t, cl.List(context.TODO(), eventList))
+	assert.Len(t, eventList.Items, 2)
+	reasons := map[string]apiv1.Event{}
+	for _, event := range eventList.Items {
+		reasons[event.Reason] = event
+		assert.Equal(t, "default", event.Namespace)
+		assert.Equal(t, "SparkApplication", event.InvolvedObject.Kind)
+		assert.Equal(t, v1beta2.SchemeGroupVersion.String(), event.InvolvedObject.APIVersion)
+		assert.Equal(t, "test-app", event.InvolvedObject.Name)
+		assert.Equal(t, app.UID, event.InvolvedObject.UID)
+		assert.Equal(t, Component, event.Source.Component)
+	}
+	assert.Equal(t, "Created Pod default/test-app-driver", reasons[ReasonDriverPodCreated].Message)
+	assert.Equal(t, apiv1.EventTypeWarning, reasons[ReasonRolledBack].Type)
+	assert.Equal(t, "1", reasons[ReasonRolledBack].Annotations["submission"])
+}
+
+func TestClientRecorderDoesNotBlockOnTheAPIServer(t *testing.T) {
+	app := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"}}
+	unblock := make(chan struct{})
+	cl := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, client ctrlClient.WithWatch, obj ctrlClient.Object, opts ...ctrlClient.CreateOption) error {
+			<-unblock
+			return client.Create(ctx, obj, opts...)
+		},
+	}).Build()
+	recorder := NewRecorder(context.TODO(), cl, logr.Discard())
+
+	recorded := make(chan struct{})
+	go func() {
+		// One event is taken by the blocked write, the queue takes queueLength more and the rest are dropped
+		for i := 0; i < queueLength+10; i++ {
+			recorder.Eventf(app, apiv1.EventTypeNormal, ReasonDriverPodCreated, "Created Pod %d", i)
+		}
+		close(recorded)
+	}()
+	select {
+	case <-recorded:
+	case <-time.After(5 * time.Second):
+		t.Fatal("recording events blocked on the API server")
+	}
+
+	close(unblock)
+	recorder.Close()
+	assert.NoError(t, recorder.Wait(context.TODO()))
+	eventList := &apiv1.EventList{}
+	assert.NoError(t, cl.List(context.TODO(), eventList))
+	assert.LessOrEqual(t, len(eventList.Items), queueLength+1)
+	assert.NotEmpty(t, eventList.Items)
+}
+
+func TestClientRecorderStopsWithItsContext(t *testing.T) {
+	app := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default"}}
+	cl := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, client ctrlClient.WithWatch, obj ctrlClient.Object, opts ...ctrlClient.CreateOption) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}).Build()
+	ctx, cancel := context.WithCancel(context.TODO())
+	recorder := NewRecorder(ctx, cl, logr.Discard())
+	recorder.Event(app, apiv1.EventTypeNormal, ReasonResourcesRendered, "Rendered")
+	recorder.Close()
+	cancel()
+
+	waitCtx, waitCancel := context.WithTimeout(context.TODO(), time.Second)
+	defer waitCancel()
+	assert.NoError(t, recorder.Wait(waitCtx))
+}
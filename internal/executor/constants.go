@@ -0,0 +1,18 @@
+package executor
+
+const (
+	// PodTemplateFileName is the key of the executor pod template in the Spark Application ConfigMap, named like
+	// the template file spark-submit mounts on the driver
+	PodTemplateFileName = "pod-spec-template.yml"
+	// PodTemplateFilePath is where the driver finds the executor pod template, next to spark.properties
+	PodTemplateFilePath = "/opt/spark/conf/" + PodTemplateFileName
+	// PodTemplateContainerName is the name of the Spark executor container of the template
+	PodTemplateContainerName = "spark-kubernetes-executor"
+
+	SparkExecutorPodTemplateFileKey          = "spark.kubernetes.executor.podTemplateFile"
+	SparkExecutorPodTemplateContainerNameKey = "spark.kubernetes.executor.podTemplateContainerName"
+	// SparkLocalDirVolumePrefix is the volume name prefix of the local dirs, which Spark mounts from the
+	// spark.kubernetes.executor.volumes properties rather than from the template
+	SparkLocalDirVolumePrefix = "spark-local-dir-"
+	ConfigMapVolumeExtension  = "-vol"
+)
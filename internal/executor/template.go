@@ -0,0 +1,189 @@
+package executor
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/kubeflow/spark-operator/api/v1beta2"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// BuildPodTemplate builds the executor pod template holding the parts of the executor spec that Spark properties
+// cannot express: affinity, tolerations, volumes, sidecars, init containers, security contexts, DNS config and host
+// aliases. Spark merges the template into every executor pod it creates. Nil is returned when the executor spec holds
+// none of them, or when sparkConf already names a template file of its own.
+func BuildPodTemplate(app *v1beta2.SparkApplication) *apiv1.Pod {
+	if _, exists := app.Spec.SparkConf[SparkExecutorPodTemplateFileKey]; exists {
+		return nil
+	}
+	executor := app.Spec.Executor
+
+	template := &apiv1.Pod{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: apiv1.SchemeGroupVersion.String(),
+			Kind:       "Pod",
+		},
+	}
+	if executor.Template != nil {
+		template.ObjectMeta = *executor.Template.ObjectMeta.DeepCopy()
+		template.Spec = *executor.Template.Spec.DeepCopy()
+	}
+	container := takeExecutorContainer(&template.Spec)
+
+	podSpec := &template.Spec
+	if executor.Affinity != nil {
+		podSpec.Affinity = executor.Affinity
+	}
+	podSpec.Tolerations = append(podSpec.Tolerations, executor.Tolerations...)
+	if executor.PodSecurityContext != nil {
+		podSpec.SecurityContext = executor.PodSecurityContext
+	}
+	if executor.SchedulerName != nil {
+		podSpec.SchedulerName = *executor.SchedulerName
+	}
+	if executor.HostNetwork != nil {
+		podSpec.HostNetwork = *executor.HostNetwork
+	}
+	if podSpec.HostNetwork {
+		podSpec.DNSPolicy = apiv1.DNSClusterFirstWithHostNet
+	}
+	if executor.DNSConfig != nil {
+		podSpec.DNSConfig = executor.DNSConfig
+	}
+	podSpec.HostAliases = append(podSpec.HostAliases, executor.HostAliases...)
+	if executor.ShareProcessNamespace != nil {
+		podSpec.ShareProcessNamespace = executor.ShareProcessNamespace
+	}
+	if executor.TerminationGracePeriodSeconds != nil {
+		podSpec.TerminationGracePeriodSeconds = executor.TerminationGracePeriodSeconds
+	}
+	if executor.PriorityClassName != nil {
+		podSpec.PriorityClassName = *executor.PriorityClassName
+	}
+
+	//Executor container
+	if executor.SecurityContext != nil {
+		container.SecurityContext = executor.SecurityContext
+	}
+	if executor.Lifecycle != nil {
+		container.Lifecycle = executor.Lifecycle
+	}
+	container.Env = append(container.Env, executor.Env...)
+	container.EnvFrom = append(container.EnvFrom, executor.EnvFrom...)
+	for _, port := range executor.Ports {
+		container.Ports = append(container.Ports, apiv1.ContainerPort{
+			Name:          port.Name,
+			ContainerPort: port.ContainerPort,
+			Protocol:      apiv1.Protocol(port.Protocol),
+		})
+	}
+	for _, configMap := range executor.ConfigMaps {
+		podSpec.Volumes = append(podSpec.Volumes, apiv1.Volume{
+			Name: configMap.Name + ConfigMapVolumeExtension,
+			VolumeSource: apiv1.VolumeSource{
+				ConfigMap: &apiv1.ConfigMapVolumeSource{LocalObjectReference: apiv1.LocalObjectReference{Name: configMap.Name}},
+			},
+		})
+		container.VolumeMounts = append(container.VolumeMounts, apiv1.VolumeMount{
+			Name:      configMap.Name + ConfigMapVolumeExtension,
+			MountPath: configMap.Path,
+		})
+	}
+	container.VolumeMounts = append(container.VolumeMounts, mountableVolumeMounts(app, executor.VolumeMounts)...)
+
+	podSpec.InitContainers = append(podSpec.InitContainers, executor.InitContainers...)
+	podSpec.Containers = append([]apiv1.Container{container}, podSpec.Containers...)
+	podSpec.Containers = append(podSpec.Containers, executor.Sidecars...)
+	podSpec.Volumes = append(podSpec.Volumes, referencedVolumes(app, podSpec)...)
+
+	if isEmptyPodTemplate(template) {
+		return nil
+	}
+	return template
+}
+
+// HasPodTemplate reports whether BuildPodTemplate renders an executor pod template for the Spark Application
+func HasPodTemplate(app *v1beta2.SparkApplication) bool {
+	return BuildPodTemplate(app) != nil
+}
+
+// RenderPodTemplate renders the executor pod template of the Spark Application as the YAML document Spark reads, or
+// returns an empty string when it has none
+func RenderPodTemplate(app *v1beta2.SparkApplication) (string, error) {
+	template := BuildPodTemplate(app)
+	if template == nil {
+		return "", nil
+	}
+	data, err := yaml.Marshal(template)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode the executor pod template: %w", err)
+	}
+	return string(data), nil
+}
+
+// takeExecutorContainer Helper func to remove the Spark executor container from the pod spec of a template supplied
+// in the executor spec, returning a new one when there is none
+func takeExecutorContainer(podSpec *apiv1.PodSpec) apiv1.Container {
+	for index, container := range podSpec.Containers {
+		if container.Name == PodTemplateContainerName {
+			podSpec.Containers = append(podSpec.Containers[:index], podSpec.Containers[index+1:]...)
+			return container
+		}
+	}
+	return apiv1.Container{Name: PodTemplateContainerName}
+}
+
+// mountableVolumeMounts Helper func to keep the volume mounts of app volumes, as Spark mounts the local dirs itself
+func mountableVolumeMounts(app *v1beta2.SparkApplication, volumeMounts []apiv1.VolumeMount) []apiv1.VolumeMount {
+	var mountable []apiv1.VolumeMount
+	for _, volumeMount := range volumeMounts {
+		if volume := findVolume(app.Spec.Volumes, volumeMount.Name); volume != nil && !isLocalDirVolume(volume.Name) {
+			mountable = append(mountable, volumeMount)
+		}
+	}
+	return mountable
+}
+
+// referencedVolumes Helper func to get the app volumes mounted by any container of the template, in app spec order.
+// Volumes the template already declares are skipped.
+func referencedVolumes(app *v1beta2.SparkApplication, podSpec *apiv1.PodSpec) []apiv1.Volume {
+	mounted := make(map[string]bool)
+	for _, containers := range [][]apiv1.Container{podSpec.InitContainers, podSpec.Containers} {
+		for _, container := range containers {
+			for _, volumeMount := range container.VolumeMounts {
+				mounted[volumeMount.Name] = true
+			}
+		}
+	}
+	var volumes []apiv1.Volume
+	for _, volume := range app.Spec.Volumes {
+		if mounted[volume.Name] && !isLocalDirVolume(volume.Name) && findVolume(podSpec.Volumes, volume.Name) == nil {
+			volumes = append(volumes, volume)
+		}
+	}
+	return volumes
+}
+
+// findVolume Helper func to look a volume up by name
+func findVolume(volumes []apiv1.Volume, name string) *apiv1.Volume {
+	for index := range volumes {
+		if volumes[index].Name == name {
+			return &volumes[index]
+		}
+	}
+	return nil
+}
+
+// isLocalDirVolume Helper func to check whether a volume holds a Spark local dir
+func isLocalDirVolume(name string) bool {
+	return strings.HasPrefix(name, SparkLocalDirVolumePrefix)
+}
+
+// isEmptyPodTemplate Helper func to check whether the template holds nothing besides the bare executor container
+func isEmptyPodTemplate(template *apiv1.Pod) bool {
+	emptySpec := apiv1.PodSpec{Containers: []apiv1.Container{{Name: PodTemplateContainerName}}}
+	return reflect.DeepEqual(template.ObjectMeta, metav1.ObjectMeta{}) && reflect.DeepEqual(template.Spec, emptySpec)
+}
@@ -0,0 +1,158 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/kubeflow/spark-operator/api/v1beta2"
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestBuildPodTemplate(t *testing.T) {
+	affinity := &apiv1.Affinity{NodeAffinity: &apiv1.NodeAffinity{RequiredDuringSchedulingIgnoredDuringExecution: &apiv1.NodeSelector{
+		NodeSelectorTerms: []apiv1.NodeSelectorTerm{{MatchExpressions: []apiv1.NodeSelectorRequirement{{Key: "pool", Operator: apiv1.NodeSelectorOpIn, Values: []string{"spark"}}}}},
+	}}}
+	runAsUser := int64(1000)
+
+	tests := []struct {
+		name  string
+		app   *v1beta2.SparkApplication
+		check func(t *testing.T, template *apiv1.Pod)
+	}{
+		{
+			name: "executor spec without template fields",
+			app: &v1beta2.SparkApplication{Spec: v1beta2.SparkApplicationSpec{Executor: v1beta2.ExecutorSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{NodeSelector: map[string]string{"disk": "ssd"}},
+			}}},
+			check: func(t *testing.T, template *apiv1.Pod) {
+				assert.Nil(t, template)
+			},
+		},
+		{
+			name: "template file set in sparkConf",
+			app: &v1beta2.SparkApplication{Spec: v1beta2.SparkApplicationSpec{
+				SparkConf: map[string]string{SparkExecutorPodTemplateFileKey: "/opt/templates/executor.yaml"},
+				Executor:  v1beta2.ExecutorSpec{SparkPodSpec: v1beta2.SparkPodSpec{Affinity: affinity}},
+			}},
+			check: func(t *testing.T, template *apiv1.Pod) {
+				assert.Nil(t, template)
+			},
+		},
+		{
+			name: "pod level fields",
+			app: &v1beta2.SparkApplication{Spec: v1beta2.SparkApplicationSpec{Executor: v1beta2.ExecutorSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					Affinity:           affinity,
+					Tolerations:        []apiv1.Toleration{{Key: "dedicated", Operator: apiv1.TolerationOpExists}},
+					PodSecurityContext: &apiv1.PodSecurityContext{RunAsUser: &runAsUser},
+					SecurityContext:    &apiv1.SecurityContext{RunAsUser: &runAsUser},
+					DNSConfig:          &apiv1.PodDNSConfig{Nameservers: []string{"10.0.0.10"}},
+					HostAliases:        []apiv1.HostAlias{{IP: "10.0.0.1", Hostnames: []string{"metastore"}}},
+					InitContainers:     []apiv1.Container{{Name: "init", Image: "busybox"}},
+					Sidecars:           []apiv1.Container{{Name: "agent", Image: "agent:1"}},
+				},
+				PriorityClassName: stringptr("spark-executors"),
+			}}},
+			check: func(t *testing.T, template *apiv1.Pod) {
+				assert.Equal(t, "Pod", template.Kind)
+				assert.Equal(t, affinity, template.Spec.Affinity)
+				assert.Equal(t, "dedicated", template.Spec.Tolerations[0].Key)
+				assert.Equal(t, &runAsUser, template.Spec.SecurityContext.RunAsUser)
+				assert.Equal(t, []string{"10.0.0.10"}, template.Spec.DNSConfig.Nameservers)
+				assert.Equal(t, "metastore", template.Spec.HostAliases[0].Hostnames[0])
+				assert.Equal(t, "spark-executors", template.Spec.PriorityClassName)
+				assert.Equal(t, "init", template.Spec.InitContainers[0].Name)
+				assert.Len(t, template.Spec.Containers, 2)
+				assert.Equal(t, PodTemplateContainerName, template.Spec.Containers[0].Name)
+				assert.Equal(t, &runAsUser, template.Spec.Containers[0].SecurityContext.RunAsUser)
+				assert.Equal(t, "agent", template.Spec.Containers[1].Name)
+			},
+		},
+		{
+			name: "volumes mounted by the executor and its sidecars",
+			app: &v1beta2.SparkApplication{Spec: v1beta2.SparkApplicationSpec{
+				Volumes: []apiv1.Volume{
+					{Name: "data"},
+					{Name: "unused"},
+					{Name: "spark-local-dir-1"},
+					{Name: "logs"},
+				},
+				Executor: v1beta2.ExecutorSpec{SparkPodSpec: v1beta2.SparkPodSpec{
+					VolumeMounts: []apiv1.VolumeMount{
+						{Name: "data", MountPath: "/data"},
+						{Name: "spark-local-dir-1", MountPath: "/local"},
+						{Name: "missing", MountPath: "/missing"},
+					},
+					ConfigMaps: []v1beta2.NamePath{{Name: "log4j", Path: "/etc/log4j"}},
+					Sidecars:   []apiv1.Container{{Name: "shipper", VolumeMounts: []apiv1.VolumeMount{{Name: "logs", MountPath: "/logs"}}}},
+				}},
+			}},
+			check: func(t *testing.T, template *apiv1.Pod) {
+				var volumeNames []string
+				for _, volume := range template.Spec.Volumes {
+					volumeNames = append(volumeNames, volume.Name)
+				}
+				assert.Equal(t, []string{"log4j-vol", "data", "logs"}, volumeNames)
+				assert.Equal(t, "log4j", template.Spec.Volumes[0].ConfigMap.Name)
+				assert.Equal(t, []apiv1.VolumeMount{
+					{Name: "log4j-vol", MountPath: "/etc/log4j"},
+					{Name: "data", MountPath: "/data"},
+				}, template.Spec.Containers[0].VolumeMounts)
+			},
+		},
+		{
+			name: "template of the executor spec as base",
+			app: &v1beta2.SparkApplication{Spec: v1beta2.SparkApplicationSpec{Executor: v1beta2.ExecutorSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					Template: &apiv1.PodTemplateSpec{Spec: apiv1.PodSpec{
+						RuntimeClassName: stringptr("gvisor"),
+						Containers: []apiv1.Container{
+							{Name: "proxy"},
+							{Name: PodTemplateContainerName, WorkingDir: "/work"},
+						},
+					}},
+					Env: []apiv1.EnvVar{{Name: "TOKEN", ValueFrom: &apiv1.EnvVarSource{SecretKeyRef: &apiv1.SecretKeySelector{
+						LocalObjectReference: apiv1.LocalObjectReference{Name: "creds"}, Key: "token",
+					}}}},
+				},
+			}}},
+			check: func(t *testing.T, template *apiv1.Pod) {
+				assert.Equal(t, "gvisor", *template.Spec.RuntimeClassName)
+				assert.Len(t, template.Spec.Containers, 2)
+				assert.Equal(t, PodTemplateContainerName, template.Spec.Containers[0].Name)
+				assert.Equal(t, "/work", template.Spec.Containers[0].WorkingDir)
+				assert.Equal(t, "creds", template.Spec.Containers[0].Env[0].ValueFrom.SecretKeyRef.Name)
+				assert.Equal(t, "proxy", template.Spec.Containers[1].Name)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := BuildPodTemplate(tt.app)
+			tt.check(t, template)
+			assert.Equal(t, template != nil, HasPodTemplate(tt.app))
+		})
+	}
+}
+
+func TestRenderPodTemplate(t *testing.T) {
+	rendered, err := RenderPodTemplate(&v1beta2.SparkApplication{})
+	assert.NoError(t, err)
+	assert.Empty(t, rendered)
+
+	app := &v1beta2.SparkApplication{Spec: v1beta2.SparkApplicationSpec{Executor: v1beta2.ExecutorSpec{
+		SparkPodSpec: v1beta2.SparkPodSpec{Tolerations: []apiv1.Toleration{{Key: "dedicated", Operator: apiv1.TolerationOpExists}}},
+	}}}
+	rendered, err = RenderPodTemplate(app)
+	assert.NoError(t, err)
+
+	decoded := &apiv1.Pod{}
+	assert.NoError(t, yaml.Unmarshal([]byte(rendered), decoded))
+	assert.Equal(t, BuildPodTemplate(app), decoded)
+}
+
+func stringptr(s string) *string {
+	return &s
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// Namespace prefixes the name of every native submit metric
+	Namespace = "native_submit"
+
+	// Label names of the native submit metrics
+	LabelNamespace = "namespace"
+	LabelPhase     = "phase"
+	LabelReason    = "reason"
+	LabelResult    = "result"
+
+	// Values of LabelResult
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+
+	// Values of LabelReason for failures that are not specific to a submission phase. Failures of the API calls of a
+	// phase use the name of the phase instead.
+	ReasonValidation = "validation"
+	ReasonConflict   = "conflict"
+	ReasonTimeout    = "timeout"
+	ReasonCanceled   = "canceled"
+)
+
+// submissionBuckets ranges from 10ms to about 40s, covering a native submission up to a slow spark-submit
+var submissionBuckets = prometheus.ExponentialBuckets(0.01, 2, 13)
+
+var (
+	// SubmissionDuration observes the end-to-end duration of a submission, from resolving its identity until the
+	// Driver Pod Service exists or the submission failed
+	SubmissionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "submission_duration_seconds",
+		Help:      "End-to-end duration of native Spark Application submissions.",
+		Buckets:   submissionBuckets,
+	}, []string{LabelResult})
+
+	// PhaseDuration observes the duration of each submission phase, successful or not
+	PhaseDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "phase_duration_seconds",
+		Help:      "Duration of the render, ConfigMap, additional resources, driver pod and service phases of native submissions.",
+		Buckets:   submissionBuckets,
+	}, []string{LabelPhase})
+
+	// SubmissionsTotal counts the successful submissions per namespace
+	SubmissionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "submissions_total",
+		Help:      "Number of successful native Spark Application submissions.",
+	}, []string{LabelNamespace})
+
+	// SubmissionFailuresTotal counts the failed submissions per namespace and failure reason
+	SubmissionFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "submission_failures_total",
+		Help:      "Number of failed native Spark Application submissions.",
+	}, []string{LabelNamespace, LabelReason})
+
+	// ServiceCreationRetriesTotal counts the attempts to create a Driver Pod Service again after it was not found
+	ServiceCreationRetriesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "service_creation_retries_total",
+		Help:      "Number of times a driver service was created again because it was not found after creation.",
+	})
+)
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(
+		SubmissionDuration,
+		PhaseDuration,
+		SubmissionsTotal,
+		SubmissionFailuresTotal,
+		ServiceCreationRetriesTotal,
+	)
+}
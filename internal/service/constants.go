@@ -1,6 +1,13 @@
 package service
 
+import "time"
+
 const (
+	// ServiceReadyTimeout bounds the wait for a created driver service to be readable, before it is created again
+	ServiceReadyTimeout = 2 * time.Second
+	// ServicePollInterval is the interval between lookups of the driver service when the client cannot watch
+	ServicePollInterval = 100 * time.Millisecond
+
 	// SparkApplicationSelectorLabel is the AppID set by the spark-distribution on the driver/executors Pods.
 	SparkApplicationSelectorLabel  = "spark-app-selector"
 	DotSeparator                   = "."
@@ -12,6 +19,7 @@ const (
 	UiPort                         = 4040
 	DriverPortProperty             = "spark.driver.port"
 	ClusterIP                      = "ClusterIP"
+	ServiceShortForm               = "svc"
 	DriverBlockManagerPortProperty = "spark.driver.blockManager.port"
 	// SparkAppNameLabel is the name of the label for the SparkApplication object name.
 	SparkAppNameLabel = LabelAnnotationPrefix + "app-name"
@@ -4,24 +4,41 @@ import (
 	"context"
 	"fmt"
 	"nativesubmit/common"
+	"nativesubmit/internal/metrics"
+	"nativesubmit/internal/tracing"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/golang/glog"
+	"github.com/go-logr/logr"
 	"github.com/kubeflow/spark-operator/api/v1beta2"
+	"go.opentelemetry.io/otel/attribute"
 	apiv1 "k8s.io/api/core/v1"
 	apiErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/util/retry"
 	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 // Helper func to create Service for the Driver Pod of the Spark Application
-func Create(app *v1beta2.SparkApplication, serviceSelectorLabels map[string]string, kubeClient ctrlClient.Client, createdApplicationId string, serviceName string) error {
+func Create(ctx context.Context, app *v1beta2.SparkApplication, serviceSelectorLabels map[string]string, kubeClient ctrlClient.Client, createdApplicationId string, serviceName string) error {
+	driverPodService, err := Build(ctx, app, serviceSelectorLabels, createdApplicationId, serviceName)
+	if err != nil {
+		return err
+	}
+	_, err = CreateOrUpdate(tracing.WithApplication(ctx, app), driverPodService, kubeClient, common.SubmitOptions{})
+	return err
+}
+
+// Build renders the Service for the Driver Pod of the Spark Application without calling the API server
+func Build(ctx context.Context, app *v1beta2.SparkApplication, serviceSelectorLabels map[string]string, createdApplicationId string, serviceName string) (*apiv1.Service, error) {
 	if app == nil {
-		return fmt.Errorf("spark application cannot be nil")
+		return nil, fmt.Errorf("spark application cannot be nil")
 	}
 
 	//Service Schema populating with specific values/data
@@ -67,13 +84,17 @@ func Create(app *v1beta2.SparkApplication, serviceSelectorLabels map[string]stri
 	}
 	//Service Schema Creation
 	driverPodService := &apiv1.Service{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: apiv1.SchemeGroupVersion.String(),
+			Kind:       "Service",
+		},
 		ObjectMeta: serviceObjectMetaData,
 		Spec: apiv1.ServiceSpec{
 			ClusterIP: None,
 			Ports: []apiv1.ServicePort{
 				{
 					Name:     DriverPortName,
-					Port:     getDriverNBlockManagerPort(app, DriverPortProperty, common.DefaultDriverPort),
+					Port:     getDriverNBlockManagerPort(ctx, app, DriverPortProperty, common.DefaultDriverPort),
 					Protocol: Protocol,
 					TargetPort: intstr.IntOrString{
 						IntVal: common.DefaultDriverPort,
@@ -81,7 +102,7 @@ func Create(app *v1beta2.SparkApplication, serviceSelectorLabels map[string]stri
 				},
 				{
 					Name:     BlockManagerPortName,
-					Port:     getDriverPodBlockManagerPort(app),
+					Port:     getDriverPodBlockManagerPort(ctx, app),
 					Protocol: Protocol,
 					TargetPort: intstr.IntOrString{
 						IntVal: common.DefaultBlockManagerPort,
@@ -102,80 +123,189 @@ func Create(app *v1beta2.SparkApplication, serviceSelectorLabels map[string]stri
 			IPFamilies:      ipFamilies[:],
 		},
 	}
+	// A port of 0 lets Spark pick a random port, which cannot be exposed by the service
+	servicePorts := driverPodService.Spec.Ports[:0]
+	for _, port := range driverPodService.Spec.Ports {
+		if port.Port != 0 {
+			servicePorts = append(servicePorts, port)
+		}
+	}
+	driverPodService.Spec.Ports = servicePorts
+	return driverPodService, nil
+}
+
+// CreateOrUpdate submits a rendered Driver Pod Service to the API server and reports whether it was created or updated.
+// An existing service owned by a different Spark Application is only overwritten when ownership takeover is allowed.
+// With server-side apply, fields owned by other field managers, such as the allocated cluster IP, are preserved.
+func CreateOrUpdate(ctx context.Context, driverPodService *apiv1.Service, kubeClient ctrlClient.Client, opts common.SubmitOptions) (operationResult controllerutil.OperationResult, err error) {
+	ctx, span := tracing.StartSpan(ctx, "service.CreateOrUpdate", attribute.String("k8s.service.name", driverPodService.Name))
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
+
+	if opts.ApplyMode == common.ApplyModeServerSide {
+		operationResult, applyErr := common.ServerSideApply(ctx, kubeClient, driverPodService, "services", opts)
+		if applyErr != nil {
+			return operationResult, applyErr
+		}
+		if operationResult == controllerutil.OperationResultCreated {
+			return operationResult, createAndCheckDriverService(ctx, kubeClient, driverPodService, 5)
+		}
+		return operationResult, nil
+	}
+
+	operationResult = controllerutil.OperationResultNone
 	//K8S API Server Call to create Service
-	createServiceErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+	createServiceErr := retry.OnError(retry.DefaultRetry, common.IsRetriableConflict, func() error {
 		existingService := &apiv1.Service{}
-		err := kubeClient.Get(context.TODO(), ctrlClient.ObjectKeyFromObject(driverPodService), existingService)
+		err := kubeClient.Get(ctx, ctrlClient.ObjectKeyFromObject(driverPodService), existingService)
 
 		if apiErrors.IsNotFound(err) {
-			createErr := kubeClient.Create(context.TODO(), driverPodService)
+			createErr := kubeClient.Create(ctx, driverPodService)
 
-			if createErr == nil {
-				return createAndCheckDriverService(kubeClient, app, driverPodService, 5, serviceName)
+			if createErr != nil {
+				return fmt.Errorf("error while creating driver service: %w", createErr)
 			}
+			operationResult = controllerutil.OperationResultCreated
+			return createAndCheckDriverService(ctx, kubeClient, driverPodService, 5)
 		}
 		if err != nil {
 			return err
 		}
-
-		//Copying over the data to existing service
-		existingService.ObjectMeta = serviceObjectMetaData
+		//Merging the generated metadata into the existing service, keeping foreign labels, annotations, finalizers
+		//and its resource version
+		if err := common.AdoptExisting(existingService, driverPodService, "services", opts.AllowOwnershipTakeover); err != nil {
+			return err
+		}
 		existingService.Spec = driverPodService.Spec
-		updateErr := kubeClient.Update(context.TODO(), existingService)
+		updateErr := kubeClient.Update(ctx, existingService)
 
 		if updateErr != nil {
 			return fmt.Errorf("error while updating driver service: %w", updateErr)
 		}
+		operationResult = controllerutil.OperationResultUpdated
 
 		return updateErr
 	})
-	return createServiceErr
+	return operationResult, createServiceErr
 }
 
-func createAndCheckDriverService(kubeClient ctrlClient.Client, app *v1beta2.SparkApplication, driverPodService *apiv1.Service, attemptCount int, serviceName string) error {
-	const sleepDuration = 2000 * time.Millisecond
-	temp := &apiv1.Service{}
+// createAndCheckDriverService confirms that a freshly created driver service can be read back, creating it again
+// when it does not show up within ServiceReadyTimeout, for up to attemptCount attempts
+func createAndCheckDriverService(ctx context.Context, kubeClient ctrlClient.Client, driverPodService *apiv1.Service, attemptCount int) error {
+	logger := logr.FromContextOrDiscard(ctx).WithValues("service", driverPodService.Name)
 
-	for iteration := 0; iteration < attemptCount; iteration++ {
-		err := kubeClient.Get(context.TODO(), ctrlClient.ObjectKey{
-			Namespace: driverPodService.Namespace,
-			Name:      driverPodService.Name,
-		}, temp)
+	for attempt := 1; attempt <= attemptCount; attempt++ {
+		found, err := waitForService(ctx, kubeClient, driverPodService, ServiceReadyTimeout)
+		if err != nil {
+			return err
+		}
+		if found {
+			logger.V(1).Info("Driver service found", "attempt", attempt)
+			return nil
+		}
+		if attempt == attemptCount {
+			break
+		}
 
-		if apiErrors.IsNotFound(err) {
-			time.Sleep(sleepDuration)
-			glog.Info("Service does not exist, attempt #", iteration+2, " to create service for the app %s", app.Name)
-			driverPodService.ResourceVersion = ""
-
-			if dvrSvcErr := kubeClient.Create(context.TODO(), driverPodService); err != dvrSvcErr {
-				if !apiErrors.IsAlreadyExists(dvrSvcErr) {
-					return fmt.Errorf("Unable to create driver service : %w", dvrSvcErr)
-				} else {
-					glog.Info("Driver service already exists, ignoring attempt to create it")
-				}
+		logger.Info("Driver service does not exist, creating it again", "attempt", attempt+1)
+		driverPodService.ResourceVersion = ""
+		metrics.ServiceCreationRetriesTotal.Inc()
+		if dvrSvcErr := kubeClient.Create(ctx, driverPodService); dvrSvcErr != nil {
+			if !apiErrors.IsAlreadyExists(dvrSvcErr) {
+				return fmt.Errorf("Unable to create driver service : %w", dvrSvcErr)
+			}
+			logger.Info("Driver service already exists, ignoring attempt to create it")
+		}
+	}
+
+	return fmt.Errorf("driver service %s not found after %d attempts", driverPodService.Name, attemptCount)
+}
+
+// waitForService reports whether the service exists or shows up within timeout. Clients that support watches wait
+// for it through a watch, other clients, such as the cached client of a manager, are polled.
+func waitForService(ctx context.Context, kubeClient ctrlClient.Client, driverPodService *apiv1.Service, timeout time.Duration) (bool, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var found bool
+	var err error
+	if watchClient, canWatch := kubeClient.(ctrlClient.WithWatch); canWatch {
+		found, err = watchForService(waitCtx, watchClient, driverPodService)
+	} else {
+		err = wait.PollUntilContextCancel(waitCtx, ServicePollInterval, true, func(pollCtx context.Context) (bool, error) {
+			found, err = getService(pollCtx, kubeClient, driverPodService)
+			return found, err
+		})
+	}
+	if found {
+		return true, nil
+	}
+	if ctx.Err() != nil {
+		return false, fmt.Errorf("gave up waiting for driver service %s: %w", driverPodService.Name, ctx.Err())
+	}
+	if waitCtx.Err() != nil {
+		// Timed out rather than failed
+		return false, nil
+	}
+	return false, err
+}
+
+// watchForService Helper func to wait for the service through a watch, until it is found or ctx is done
+func watchForService(ctx context.Context, watchClient ctrlClient.WithWatch, driverPodService *apiv1.Service) (bool, error) {
+	// The watch is started before the lookup, so the service cannot be created unnoticed in between
+	watcher, err := watchClient.Watch(ctx, &apiv1.ServiceList{}, ctrlClient.InNamespace(driverPodService.Namespace),
+		ctrlClient.MatchingFieldsSelector{Selector: fields.OneTermEqualSelector("metadata.name", driverPodService.Name)})
+	if err != nil {
+		return false, fmt.Errorf("error while watching driver service: %w", err)
+	}
+	defer watcher.Stop()
+
+	if found, err := getService(ctx, watchClient, driverPodService); found || err != nil {
+		return found, err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case event, open := <-watcher.ResultChan():
+			if !open {
+				// The API server ended the watch, fall back to a final lookup
+				return getService(ctx, watchClient, driverPodService)
+			}
+			service, isService := event.Object.(*apiv1.Service)
+			if isService && service.Name == driverPodService.Name && (event.Type == watch.Added || event.Type == watch.Modified) {
+				return true, nil
 			}
-		} else {
-			glog.Info("Driver Service found in attempt", iteration+2, "for the app %s", app.Name)
-			return nil
 		}
 	}
+}
 
-	return nil
+// getService Helper func to look the service up, a missing service is not an error
+func getService(ctx context.Context, kubeClient ctrlClient.Client, driverPodService *apiv1.Service) (bool, error) {
+	err := kubeClient.Get(ctx, ctrlClient.ObjectKeyFromObject(driverPodService), &apiv1.Service{})
+	if apiErrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error while retrieving driver service: %w", err)
+	}
+	return true, nil
 }
 
-func getDriverPodBlockManagerPort(app *v1beta2.SparkApplication) int32 {
+func getDriverPodBlockManagerPort(ctx context.Context, app *v1beta2.SparkApplication) int32 {
 	if common.CheckSparkConf(app.Spec.SparkConf, DriverBlockManagerPortProperty) {
-		return getDriverNBlockManagerPort(app, DriverBlockManagerPortProperty, common.DefaultBlockManagerPort)
+		return getDriverNBlockManagerPort(ctx, app, DriverBlockManagerPortProperty, common.DefaultBlockManagerPort)
 	}
 	return common.DefaultBlockManagerPort
 }
 
-func getDriverNBlockManagerPort(app *v1beta2.SparkApplication, portConfig string, defaultPort int32) int32 {
+func getDriverNBlockManagerPort(ctx context.Context, app *v1beta2.SparkApplication, portConfig string, defaultPort int32) int32 {
 	if common.CheckSparkConf(app.Spec.SparkConf, portConfig) {
 		value, _ := app.Spec.SparkConf[portConfig]
 		portVal, parseError := strconv.ParseInt(value, 10, 64)
 		if parseError != nil {
-			glog.Errorf("failed to parse %s in namespace %s: %v", portConfig, app.Namespace, parseError)
+			logr.FromContextOrDiscard(ctx).Error(parseError, "Failed to parse port, using the default", "property", portConfig, "default", defaultPort)
 			return defaultPort
 		}
 		return int32(portVal)
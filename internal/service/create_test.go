@@ -1,14 +1,23 @@
 package service
 
 import (
+	"context"
 	"nativesubmit/common"
+	"nativesubmit/internal/metrics"
 	"testing"
+	"time"
 
 	v1beta2 "github.com/kubeflow/spark-operator/api/v1beta2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 func TestCreate(t *testing.T) {
@@ -83,7 +92,7 @@ func TestCreate(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			client := fake.NewClientBuilder().WithScheme(scheme).Build()
-			err := Create(tt.app, tt.serviceSelectorLabels, client, tt.createdApplicationId, tt.serviceName)
+			err := Create(context.TODO(), tt.app, tt.serviceSelectorLabels, client, tt.createdApplicationId, tt.serviceName)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -93,6 +102,128 @@ func TestCreate(t *testing.T) {
 	}
 }
 
+func TestBuild(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		Spec: v1beta2.SparkApplicationSpec{
+			SparkConf: map[string]string{
+				"spark.driver.port": "7079",
+			},
+		},
+	}
+	selector := map[string]string{"spark-role": "driver"}
+
+	svc, err := Build(context.TODO(), app, selector, "test-app-id", "test-service")
+	assert.NoError(t, err)
+	assert.Equal(t, "test-service", svc.Name)
+	assert.Equal(t, "test-app-id", svc.Labels[SparkApplicationSelectorLabel])
+	assert.Equal(t, selector, svc.Spec.Selector)
+	assert.Equal(t, int32(7079), svc.Spec.Ports[0].Port)
+
+	_, err = Build(context.TODO(), nil, selector, "test-app-id", "test-service")
+	assert.Error(t, err)
+
+	app.Spec.SparkConf["spark.driver.port"] = "0"
+	svc, err = Build(context.TODO(), app, selector, "test-app-id", "test-service")
+	assert.NoError(t, err)
+	for _, port := range svc.Spec.Ports {
+		assert.NotEqual(t, DriverPortName, port.Name)
+		assert.NotZero(t, port.Port)
+	}
+}
+
+func TestCreateOrUpdateKeepsForeignMetadata(t *testing.T) {
+	app := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default", UID: "test-app-uid"}}
+	svc, err := Build(context.TODO(), app, map[string]string{"spark-role": "driver"}, "test-app-id", "test-service")
+	assert.NoError(t, err)
+
+	existing := svc.DeepCopy()
+	existing.Labels = map[string]string{"team": "data", SparkApplicationSelectorLabel: "old-app-id"}
+	existing.Annotations = map[string]string{"example.com/note": "keep"}
+	existing.Finalizers = []string{"example.com/finalizer"}
+	client := fake.NewClientBuilder().WithObjects(existing).Build()
+
+	operationResult, err := CreateOrUpdate(context.TODO(), svc, client, common.SubmitOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, controllerutil.OperationResultUpdated, operationResult)
+
+	updated := &corev1.Service{}
+	assert.NoError(t, client.Get(context.TODO(), ctrlClient.ObjectKeyFromObject(svc), updated))
+	assert.Equal(t, "data", updated.Labels["team"])
+	assert.Equal(t, "test-app-id", updated.Labels[SparkApplicationSelectorLabel])
+	assert.Equal(t, "keep", updated.Annotations["example.com/note"])
+	assert.Equal(t, []string{"example.com/finalizer"}, updated.Finalizers)
+	assert.Equal(t, svc.OwnerReferences, updated.OwnerReferences)
+}
+
+func TestCreateAndCheckDriverServiceHonoursCancellation(t *testing.T) {
+	client := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, c ctrlClient.WithWatch, key ctrlClient.ObjectKey, obj ctrlClient.Object, opts ...ctrlClient.GetOption) error {
+			return apiErrors.NewNotFound(corev1.Resource("services"), key.Name)
+		},
+	}).Build()
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "test-service", Namespace: "default"}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := createAndCheckDriverService(ctx, client, svc, 5)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestCreateAndCheckDriverServiceCountsRetries(t *testing.T) {
+	notFoundGets := 1
+	client := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, c ctrlClient.WithWatch, key ctrlClient.ObjectKey, obj ctrlClient.Object, opts ...ctrlClient.GetOption) error {
+			if notFoundGets > 0 {
+				notFoundGets--
+				return apiErrors.NewNotFound(corev1.Resource("services"), key.Name)
+			}
+			return c.Get(ctx, key, obj, opts...)
+		},
+	}).Build()
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "test-service", Namespace: "default"}}
+
+	retries := testutil.ToFloat64(metrics.ServiceCreationRetriesTotal)
+	assert.NoError(t, createAndCheckDriverService(context.TODO(), client, svc, 5))
+	assert.Equal(t, retries+1, testutil.ToFloat64(metrics.ServiceCreationRetriesTotal))
+}
+
+func TestCreateAndCheckDriverServiceWaitsForService(t *testing.T) {
+	tests := []struct {
+		name string
+		// wrap optionally hides the Watch method of the client
+		wrap func(c ctrlClient.WithWatch) ctrlClient.Client
+	}{
+		{
+			name: "watch",
+			wrap: func(c ctrlClient.WithWatch) ctrlClient.Client { return c },
+		},
+		{
+			name: "poll without watch support",
+			wrap: func(c ctrlClient.WithWatch) ctrlClient.Client { return struct{ ctrlClient.Client }{c} },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClient := fake.NewClientBuilder().Build()
+			svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "test-service", Namespace: "default"}}
+			// The service shows up shortly after the first lookup, as it does with a lagging cache
+			go func() {
+				time.Sleep(200 * time.Millisecond)
+				_ = fakeClient.Create(context.TODO(), svc.DeepCopy())
+			}()
+
+			retries := testutil.ToFloat64(metrics.ServiceCreationRetriesTotal)
+			start := time.Now()
+			assert.NoError(t, createAndCheckDriverService(context.TODO(), tt.wrap(fakeClient), svc, 5))
+			assert.Less(t, time.Since(start), ServiceReadyTimeout)
+			assert.Equal(t, retries, testutil.ToFloat64(metrics.ServiceCreationRetriesTotal))
+		})
+	}
+}
+
 func TestGetDriverPodBlockManagerPort(t *testing.T) {
 	tests := []struct {
 		name string
@@ -121,7 +252,7 @@ func TestGetDriverPodBlockManagerPort(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := getDriverPodBlockManagerPort(tt.app)
+			got := getDriverPodBlockManagerPort(context.TODO(), tt.app)
 			assert.Equal(t, tt.want, got)
 		})
 	}
@@ -174,7 +305,7 @@ func TestGetDriverNBlockManagerPort(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := getDriverNBlockManagerPort(tt.app, tt.portConfig, tt.defaultPort)
+			got := getDriverNBlockManagerPort(context.TODO(), tt.app, tt.portConfig, tt.defaultPort)
 			assert.Equal(t, tt.want, got)
 		})
 	}
@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/kubeflow/spark-operator/api/v1beta2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// ScopeName is the instrumentation scope of the spans started by native submit
+	ScopeName = "nativesubmit"
+
+	// TraceParentAnnotation holds a W3C traceparent the submission trace continues from, e.g. the one of the
+	// request that created the Spark Application
+	TraceParentAnnotation = "sparkoperator.k8s.io/traceparent"
+	// TraceStateAnnotation optionally holds the W3C tracestate accompanying TraceParentAnnotation
+	TraceStateAnnotation = "sparkoperator.k8s.io/tracestate"
+
+	// Attributes describing the Spark Application every span belongs to
+	AttributeAppName            = attribute.Key("spark.app.name")
+	AttributeNamespace          = attribute.Key("k8s.namespace.name")
+	AttributeSubmissionID       = attribute.Key("spark.submission.id")
+	AttributeSparkApplicationID = attribute.Key("spark.app.id")
+)
+
+// applicationAttributesKey is the context key of the attributes added to every span started with StartSpan
+type applicationAttributesKey struct{}
+
+// ExtractTraceContext returns ctx with the remote span context of the traceparent annotation of app as parent, so
+// the spans of the submission continue that trace. ctx is returned as is when the annotation is missing or invalid.
+func ExtractTraceContext(ctx context.Context, app *v1beta2.SparkApplication) context.Context {
+	traceParent, exists := app.Annotations[TraceParentAnnotation]
+	if !exists {
+		return ctx
+	}
+	carrier := propagation.MapCarrier{"traceparent": traceParent}
+	if traceState, exists := app.Annotations[TraceStateAnnotation]; exists {
+		carrier["tracestate"] = traceState
+	}
+	return propagation.TraceContext{}.Extract(ctx, carrier)
+}
+
+// WithApplication records the attributes describing app on the span of ctx, and returns a context that adds them to
+// the spans started from it with StartSpan. It is called again once the generated identifiers are known.
+func WithApplication(ctx context.Context, app *v1beta2.SparkApplication) context.Context {
+	attributes := []attribute.KeyValue{
+		AttributeAppName.String(app.Name),
+		AttributeNamespace.String(app.Namespace),
+		AttributeSubmissionID.String(app.Status.SubmissionID),
+		AttributeSparkApplicationID.String(app.Status.SparkApplicationID),
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attributes...)
+	return context.WithValue(ctx, applicationAttributesKey{}, attributes)
+}
+
+// StartSpan starts a span carrying the application attributes of ctx and the given attributes
+func StartSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	applicationAttributes, _ := ctx.Value(applicationAttributesKey{}).([]attribute.KeyValue)
+	return otel.Tracer(ScopeName).Start(ctx, name, trace.WithAttributes(append(applicationAttributes, attributes...)...))
+}
+
+// EndSpan ends span, recording err on it when the traced call failed
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
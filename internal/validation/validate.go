@@ -0,0 +1,524 @@
+package validation
+
+import (
+	"fmt"
+	"nativesubmit/common"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kubeflow/spark-operator/api/v1beta2"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	apimachineryvalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	// sparkConfDir is where the driver and executor pods mount their Spark configuration
+	sparkConfDir = "/opt/spark/conf"
+	// configMapVolumeExtension is appended to the name of a config map to name its volume
+	configMapVolumeExtension     = "-vol"
+	kerberosKrb5PathKey          = "spark.kubernetes.kerberos.krb5.path"
+	kerberosKrb5ConfigMapNameKey = "spark.kubernetes.kerberos.krb5.configMapName"
+	kerberosTokenSecretNameKey   = "spark.kubernetes.kerberos.tokenSecret.name"
+	kerberosTokenSecretItemKey   = "spark.kubernetes.kerberos.tokenSecret.itemKey"
+	kerberosKeytabKey            = "spark.kerberos.keytab"
+	kerberosPrincipalKey         = "spark.kerberos.principal"
+	oauthTokenKey                = "spark.kubernetes.authenticate.driver.oauthToken"
+	oauthTokenFileKey            = "spark.kubernetes.authenticate.driver.oauthTokenFile"
+	executorSecretKeyRefPrefix   = "spark.kubernetes.executor.secretKeyRef."
+)
+
+// jvmMemoryPattern matches JVM memory strings such as 512m, 2g or 1024, the format Spark expects for memory settings
+var jvmMemoryPattern = regexp.MustCompile(`^[0-9]+([kKmMgGtT][bB]?)?$`)
+
+// Validate checks a Spark Application for every problem that would otherwise surface, or panic, while its
+// resources are built. All problems are reported at once, with the path of the offending field.
+func Validate(app *v1beta2.SparkApplication) field.ErrorList {
+	var allErrs field.ErrorList
+	if app == nil {
+		return append(allErrs, field.Required(field.NewPath(""), "spark application cannot be nil"))
+	}
+	specPath := field.NewPath("spec")
+	sparkConfPath := specPath.Child("sparkConf")
+
+	allErrs = append(allErrs, validateNames(app)...)
+	allErrs = append(allErrs, validateImage(app, specPath)...)
+	allErrs = append(allErrs, validatePorts(app, specPath)...)
+	allErrs = append(allErrs, validateMemory(app, specPath)...)
+	allErrs = append(allErrs, validateCPU(app, specPath)...)
+	allErrs = append(allErrs, validatePodMetadata(app.Spec.Driver.SparkPodSpec, specPath.Child("driver"))...)
+	allErrs = append(allErrs, validatePodMetadata(app.Spec.Executor.SparkPodSpec, specPath.Child("executor"))...)
+	allErrs = append(allErrs, metav1validation.ValidateLabels(app.Spec.Driver.ServiceLabels, specPath.Child("driver", "serviceLabels"))...)
+	allErrs = append(allErrs, apimachineryvalidation.ValidateAnnotations(app.Spec.Driver.ServiceAnnotations, specPath.Child("driver", "serviceAnnotations"))...)
+	allErrs = append(allErrs, validateSparkConfMetadata(app.Spec.SparkConf, sparkConfPath)...)
+	allErrs = append(allErrs, validateVolumes(app, specPath)...)
+	allErrs = append(allErrs, validateDriverScheduling(app, specPath)...)
+	allErrs = append(allErrs, validateHostNetworkPorts(app, specPath)...)
+	allErrs = append(allErrs, validateKerberos(app.Spec.SparkConf, specPath.Child("sparkConf"))...)
+	allErrs = append(allErrs, validateSecretKeyRefs(app, specPath)...)
+	if app.Spec.SparkConf[oauthTokenKey] != "" && app.Spec.SparkConf[oauthTokenFileKey] != "" {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("sparkConf").Key(oauthTokenFileKey),
+			fmt.Sprintf("may not be set together with %s", oauthTokenKey)))
+	}
+	return allErrs
+}
+
+// validateNames Helper func to check the names the driver pod and its configmap are created with
+func validateNames(app *v1beta2.SparkApplication) field.ErrorList {
+	var allErrs field.ErrorList
+	if app.Name == "" {
+		return append(allErrs, field.Required(field.NewPath("metadata", "name"), ""))
+	}
+
+	driverPodNamePath := field.NewPath("metadata", "name")
+	if app.Spec.Driver.PodName != nil && len(*app.Spec.Driver.PodName) > 0 {
+		driverPodNamePath = field.NewPath("spec", "driver", "podName")
+	} else if app.Spec.SparkConf[common.SparkDriverPodNameKey] != "" {
+		driverPodNamePath = field.NewPath("spec", "sparkConf").Key(common.SparkDriverPodNameKey)
+	}
+	driverPodName := common.GetDriverPodName(app)
+	// The configmap is named after the driver pod, so its name is the longer of the two
+	for _, msg := range validation.IsDNS1123Subdomain(driverPodName + "-conf-map") {
+		allErrs = append(allErrs, field.Invalid(driverPodNamePath, driverPodName, fmt.Sprintf("driver pod name: %s", msg)))
+	}
+	return allErrs
+}
+
+// validateImage Helper func to check that the driver container image is set somewhere
+func validateImage(app *v1beta2.SparkApplication, specPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	images := []string{app.Spec.SparkConf["spark.kubernetes.driver.container.image"], app.Spec.SparkConf["spark.kubernetes.container.image"]}
+	if app.Spec.Driver.Image != nil {
+		images = append(images, *app.Spec.Driver.Image)
+	}
+	if app.Spec.Image != nil {
+		images = append(images, *app.Spec.Image)
+	}
+	for _, image := range images {
+		if strings.TrimSpace(image) != "" {
+			return allErrs
+		}
+	}
+	return append(allErrs, field.Required(specPath.Child("image"), "set spec.image, spec.driver.image or spark.kubernetes.container.image"))
+}
+
+// validatePorts Helper func to check the ports exposed by the driver pod and its service
+func validatePorts(app *v1beta2.SparkApplication, specPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for _, key := range []string{common.SparkDriverPort, "spark.blockManager.port", "spark.driver.blockManager.port"} {
+		value, exists := app.Spec.SparkConf[key]
+		if !exists {
+			continue
+		}
+		port, err := strconv.Atoi(value)
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("sparkConf").Key(key), value, "must be an integer"))
+			continue
+		}
+		// Spark binds a random port when the port is 0
+		if port == 0 {
+			continue
+		}
+		for _, msg := range validation.IsValidPortNum(port) {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("sparkConf").Key(key), value, msg))
+		}
+	}
+	allErrs = append(allErrs, validateContainerPorts(app.Spec.Driver.Ports, specPath.Child("driver", "ports"))...)
+	allErrs = append(allErrs, validateContainerPorts(app.Spec.Executor.Ports, specPath.Child("executor", "ports"))...)
+	return allErrs
+}
+
+// Helper func to check the additional ports of a driver or executor
+func validateContainerPorts(ports []v1beta2.Port, portsPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for index, port := range ports {
+		for _, msg := range validation.IsValidPortNum(int(port.ContainerPort)) {
+			allErrs = append(allErrs, field.Invalid(portsPath.Index(index).Child("containerPort"), port.ContainerPort, msg))
+		}
+	}
+	return allErrs
+}
+
+// validateMemory Helper func to check the memory settings, which are JVM memory strings rather than Kubernetes quantities
+func validateMemory(app *v1beta2.SparkApplication, specPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	memorySettings := map[*field.Path]*string{
+		specPath.Child("driver", "memory"):           app.Spec.Driver.Memory,
+		specPath.Child("driver", "memoryOverhead"):   app.Spec.Driver.MemoryOverhead,
+		specPath.Child("executor", "memory"):         app.Spec.Executor.Memory,
+		specPath.Child("executor", "memoryOverhead"): app.Spec.Executor.MemoryOverhead,
+	}
+	for _, key := range []string{"spark.driver.memory", "spark.driver.memoryOverhead", "spark.executor.memory",
+		"spark.executor.memoryOverhead", "spark.kubernetes.memoryOverhead"} {
+		if value, exists := app.Spec.SparkConf[key]; exists {
+			memorySettings[specPath.Child("sparkConf").Key(key)] = &value
+		}
+	}
+	for memoryPath, memory := range memorySettings {
+		if memory != nil && !jvmMemoryPattern.MatchString(strings.TrimSpace(*memory)) {
+			allErrs = append(allErrs, field.Invalid(memoryPath, *memory, "must be a JVM memory string such as 512m or 2g"))
+		}
+	}
+
+	overheadFactors := map[*field.Path]*string{specPath.Child("memoryOverheadFactor"): app.Spec.MemoryOverheadFactor}
+	if value, exists := app.Spec.SparkConf["spark.driver.memoryOverheadFactor"]; exists {
+		overheadFactors[specPath.Child("sparkConf").Key("spark.driver.memoryOverheadFactor")] = &value
+	}
+	for factorPath, factor := range overheadFactors {
+		if factor == nil {
+			continue
+		}
+		if value, err := strconv.ParseFloat(*factor, 64); err != nil || value < 0 {
+			allErrs = append(allErrs, field.Invalid(factorPath, *factor, "must be a non-negative number"))
+		}
+	}
+	return sortErrors(allErrs)
+}
+
+// validateCPU Helper func to check the core counts and the cpu requests and limits
+func validateCPU(app *v1beta2.SparkApplication, specPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	cores := map[*field.Path]*int32{
+		specPath.Child("driver", "cores"):   app.Spec.Driver.Cores,
+		specPath.Child("executor", "cores"): app.Spec.Executor.Cores,
+	}
+	for coresPath, value := range cores {
+		if value != nil && *value <= 0 {
+			allErrs = append(allErrs, field.Invalid(coresPath, *value, "must be greater than zero"))
+		}
+	}
+	for _, key := range []string{"spark.driver.cores", "spark.executor.cores"} {
+		value, exists := app.Spec.SparkConf[key]
+		if !exists {
+			continue
+		}
+		if count, err := strconv.ParseInt(value, 10, 32); err != nil || count <= 0 {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("sparkConf").Key(key), value, "must be a positive integer"))
+		}
+	}
+
+	quantities := map[*field.Path]*string{
+		specPath.Child("driver", "coreLimit"):     app.Spec.Driver.CoreLimit,
+		specPath.Child("driver", "coreRequest"):   app.Spec.Driver.CoreRequest,
+		specPath.Child("executor", "coreLimit"):   app.Spec.Executor.CoreLimit,
+		specPath.Child("executor", "coreRequest"): app.Spec.Executor.CoreRequest,
+	}
+	for _, key := range []string{common.SparkDriverCoreLimitKey, common.SparkDriverCoreRequestKey,
+		"spark.kubernetes.executor.limit.cores", "spark.kubernetes.executor.request.cores"} {
+		if value, exists := app.Spec.SparkConf[key]; exists {
+			quantities[specPath.Child("sparkConf").Key(key)] = &value
+		}
+	}
+	for quantityPath, value := range quantities {
+		if value == nil {
+			continue
+		}
+		if _, err := resource.ParseQuantity(*value); err != nil {
+			allErrs = append(allErrs, field.Invalid(quantityPath, *value, "must be a cpu quantity such as 500m or 2"))
+		}
+	}
+	return sortErrors(allErrs)
+}
+
+// validatePodMetadata Helper func to check the labels and annotations of a driver or executor
+func validatePodMetadata(podSpec v1beta2.SparkPodSpec, podPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	allErrs = append(allErrs, metav1validation.ValidateLabels(podSpec.Labels, podPath.Child("labels"))...)
+	allErrs = append(allErrs, apimachineryvalidation.ValidateAnnotations(podSpec.Annotations, podPath.Child("annotations"))...)
+	return allErrs
+}
+
+// validateSparkConfMetadata Helper func to check the labels and annotations passed as spark.kubernetes.*.label.* and
+// spark.kubernetes.*.annotation.* properties
+func validateSparkConfMetadata(sparkConf map[string]string, sparkConfPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for key, value := range sparkConf {
+		for _, role := range []string{"driver", "executor"} {
+			if labelKey, isLabel := strings.CutPrefix(key, "spark.kubernetes."+role+".label."); isLabel {
+				allErrs = append(allErrs, metav1validation.ValidateLabels(map[string]string{labelKey: value}, sparkConfPath.Key(key))...)
+			}
+			if annotationKey, isAnnotation := strings.CutPrefix(key, "spark.kubernetes."+role+".annotation."); isAnnotation {
+				allErrs = append(allErrs, apimachineryvalidation.ValidateAnnotations(map[string]string{annotationKey: value}, sparkConfPath.Key(key))...)
+			}
+		}
+	}
+	return sortErrors(allErrs)
+}
+
+// validateVolumes Helper func to check the volume names and that every volume mount refers to a declared volume
+func validateVolumes(app *v1beta2.SparkApplication, specPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	volumeNames := sets.New[string]()
+	for index, volume := range app.Spec.Volumes {
+		namePath := specPath.Child("volumes").Index(index).Child("name")
+		for _, msg := range validation.IsDNS1123Label(volume.Name) {
+			allErrs = append(allErrs, field.Invalid(namePath, volume.Name, msg))
+		}
+		if volumeNames.Has(volume.Name) {
+			allErrs = append(allErrs, field.Duplicate(namePath, volume.Name))
+		}
+		volumeNames.Insert(volume.Name)
+	}
+	allErrs = append(allErrs, validateVolumeMounts(app.Spec.Driver.VolumeMounts, volumeNames, specPath.Child("driver", "volumeMounts"))...)
+	allErrs = append(allErrs, validateVolumeMounts(app.Spec.Executor.VolumeMounts, volumeNames, specPath.Child("executor", "volumeMounts"))...)
+	allErrs = append(allErrs, validateConfigMapName(app.Spec.SparkConfigMap, specPath.Child("sparkConfigMap"))...)
+	allErrs = append(allErrs, validateConfigMapName(app.Spec.HadoopConfigMap, specPath.Child("hadoopConfigMap"))...)
+	allErrs = append(allErrs, validateConfigMaps(app.Spec.Driver.ConfigMaps, volumeNames, specPath.Child("driver", "configMaps"))...)
+	allErrs = append(allErrs, validateConfigMaps(app.Spec.Executor.ConfigMaps, volumeNames, specPath.Child("executor", "configMaps"))...)
+	return allErrs
+}
+
+// validateConfigMapName Helper func to check the name of an optional config map the pods mount
+func validateConfigMapName(configMapName *string, configMapPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if configMapName == nil {
+		return allErrs
+	}
+	for _, msg := range validation.IsDNS1123Subdomain(*configMapName) {
+		allErrs = append(allErrs, field.Invalid(configMapPath, *configMapName, msg))
+	}
+	return allErrs
+}
+
+// validateConfigMaps Helper func to check the config maps of a driver or executor. Each one becomes a "<name>-vol"
+// volume, which must not clash with a declared volume, and must not be mounted over the Spark conf directory, which
+// holds the spark.properties file the pod is started with.
+func validateConfigMaps(configMaps []v1beta2.NamePath, volumeNames sets.Set[string], configMapsPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	configMapNames := sets.New[string]()
+	mountPaths := sets.New[string]()
+	for index, configMap := range configMaps {
+		namePath := configMapsPath.Index(index).Child("name")
+		for _, msg := range validation.IsDNS1123Subdomain(configMap.Name) {
+			allErrs = append(allErrs, field.Invalid(namePath, configMap.Name, msg))
+		}
+		if configMapNames.Has(configMap.Name) {
+			allErrs = append(allErrs, field.Duplicate(namePath, configMap.Name))
+		} else if volumeNames.Has(configMap.Name + configMapVolumeExtension) {
+			allErrs = append(allErrs, field.Invalid(namePath, configMap.Name, fmt.Sprintf("volume %s%s is already declared in spec.volumes", configMap.Name, configMapVolumeExtension)))
+		}
+		configMapNames.Insert(configMap.Name)
+
+		mountPathPath := configMapsPath.Index(index).Child("path")
+		if configMap.Path == "" {
+			allErrs = append(allErrs, field.Required(mountPathPath, ""))
+			continue
+		}
+		mountPath := path.Clean(configMap.Path)
+		if mountPath == sparkConfDir || strings.HasPrefix(mountPath, sparkConfDir+"/") {
+			allErrs = append(allErrs, field.Invalid(mountPathPath, configMap.Path, fmt.Sprintf("must not be %s or a directory in it, where the Spark configuration is mounted", sparkConfDir)))
+		}
+		if mountPaths.Has(mountPath) {
+			allErrs = append(allErrs, field.Duplicate(mountPathPath, configMap.Path))
+		}
+		mountPaths.Insert(mountPath)
+	}
+	return allErrs
+}
+
+// Helper func to check the volume mounts of a driver or executor against the declared volumes
+func validateVolumeMounts(volumeMounts []apiv1.VolumeMount, volumeNames sets.Set[string], volumeMountsPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for index, volumeMount := range volumeMounts {
+		mountPath := volumeMountsPath.Index(index)
+		if !volumeNames.Has(volumeMount.Name) {
+			allErrs = append(allErrs, field.NotFound(mountPath.Child("name"), volumeMount.Name))
+		}
+		if volumeMount.MountPath == "" {
+			allErrs = append(allErrs, field.Required(mountPath.Child("mountPath"), ""))
+		}
+	}
+	return allErrs
+}
+
+// validateDriverScheduling Helper func to check the priority class, scheduling gates and topology spread constraints
+// the driver pod is scheduled with
+func validateDriverScheduling(app *v1beta2.SparkApplication, specPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	driverPath := specPath.Child("driver")
+	if priorityClassName := app.Spec.Driver.PriorityClassName; priorityClassName != nil {
+		for _, msg := range validation.IsDNS1123Subdomain(*priorityClassName) {
+			allErrs = append(allErrs, field.Invalid(driverPath.Child("priorityClassName"), *priorityClassName, msg))
+		}
+	}
+
+	if app.Spec.Driver.Template != nil {
+		templatePath := driverPath.Child("template", "spec")
+		for index, gate := range app.Spec.Driver.Template.Spec.SchedulingGates {
+			for _, msg := range validation.IsQualifiedName(gate.Name) {
+				allErrs = append(allErrs, field.Invalid(templatePath.Child("schedulingGates").Index(index).Child("name"), gate.Name, msg))
+			}
+		}
+		for index, constraint := range app.Spec.Driver.Template.Spec.TopologySpreadConstraints {
+			constraintPath := templatePath.Child("topologySpreadConstraints").Index(index)
+			if constraint.MaxSkew <= 0 {
+				allErrs = append(allErrs, field.Invalid(constraintPath.Child("maxSkew"), constraint.MaxSkew, "must be greater than zero"))
+			}
+			if constraint.TopologyKey == "" {
+				allErrs = append(allErrs, field.Required(constraintPath.Child("topologyKey"), ""))
+			}
+			if constraint.WhenUnsatisfiable != apiv1.DoNotSchedule && constraint.WhenUnsatisfiable != apiv1.ScheduleAnyway {
+				allErrs = append(allErrs, field.NotSupported(constraintPath.Child("whenUnsatisfiable"), constraint.WhenUnsatisfiable,
+					[]string{string(apiv1.DoNotSchedule), string(apiv1.ScheduleAnyway)}))
+			}
+		}
+	}
+	return sortErrors(allErrs)
+}
+
+// validateHostNetworkPorts Helper func to check that the ports of the driver container and its sidecars do not
+// conflict, as a driver pod on the host network binds all of them on the node
+func validateHostNetworkPorts(app *v1beta2.SparkApplication, specPath *field.Path) field.ErrorList {
+	if app.Spec.Driver.HostNetwork == nil || !*app.Spec.Driver.HostNetwork {
+		return nil
+	}
+	var allErrs field.ErrorList
+	sparkConfPath := specPath.Child("sparkConf")
+	type hostPort struct {
+		port     int32
+		protocol apiv1.Protocol
+	}
+	claimedBy := make(map[hostPort]string)
+	claim := func(port int32, protocol apiv1.Protocol, fieldPath *field.Path, description string) {
+		if protocol == "" {
+			protocol = apiv1.ProtocolTCP
+		}
+		// A random port cannot conflict with a fixed one
+		if port == 0 {
+			return
+		}
+		key := hostPort{port: port, protocol: protocol}
+		if owner, claimed := claimedBy[key]; claimed {
+			allErrs = append(allErrs, field.Invalid(fieldPath, port, fmt.Sprintf("conflicts with %s on the host network", owner)))
+			return
+		}
+		claimedBy[key] = description
+	}
+
+	// The UI port is fixed, so a conflict is reported on the configurable port
+	claim(common.DefaultUiPort, apiv1.ProtocolTCP, specPath.Child("driver", "hostNetwork"), "the Spark UI port")
+	// Unparseable ports are reported by validatePorts
+	driverPort, _ := common.GetDriverPort(app.Spec.SparkConf)
+	claim(int32(driverPort), apiv1.ProtocolTCP, sparkConfPath.Key(common.SparkDriverPort), "the driver port")
+	blockManagerPortKey := "spark.driver.blockManager.port"
+	if _, exists := app.Spec.SparkConf[blockManagerPortKey]; !exists {
+		blockManagerPortKey = "spark.blockManager.port"
+	}
+	blockManagerPort := common.DefaultBlockManagerPort
+	if port, err := strconv.Atoi(app.Spec.SparkConf[blockManagerPortKey]); err == nil {
+		blockManagerPort = port
+	}
+	claim(int32(blockManagerPort), apiv1.ProtocolTCP, sparkConfPath.Key(blockManagerPortKey), "the block manager port")
+	for sidecarIndex, sidecar := range app.Spec.Driver.Sidecars {
+		for portIndex, port := range sidecar.Ports {
+			portPath := specPath.Child("driver", "sidecars").Index(sidecarIndex).Child("ports").Index(portIndex).Child("containerPort")
+			claim(port.ContainerPort, port.Protocol, portPath, fmt.Sprintf("a port of sidecar %s", sidecar.Name))
+		}
+	}
+	return allErrs
+}
+
+// validateKerberos Helper func to check the Kerberos properties come with the ones they need, as Spark requires
+func validateKerberos(sparkConf map[string]string, sparkConfPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if sparkConf[kerberosKrb5PathKey] != "" && sparkConf[kerberosKrb5ConfigMapNameKey] != "" {
+		allErrs = append(allErrs, field.Forbidden(sparkConfPath.Key(kerberosKrb5ConfigMapNameKey),
+			fmt.Sprintf("may not be set together with %s", kerberosKrb5PathKey)))
+	}
+	for _, key := range []string{kerberosKrb5ConfigMapNameKey, kerberosTokenSecretNameKey} {
+		if name := sparkConf[key]; name != "" {
+			for _, msg := range validation.IsDNS1123Subdomain(name) {
+				allErrs = append(allErrs, field.Invalid(sparkConfPath.Key(key), name, msg))
+			}
+		}
+	}
+	// The delegation token secret is mounted by name and read by item key
+	tokenSecretName, tokenSecretItemKey := sparkConf[kerberosTokenSecretNameKey], sparkConf[kerberosTokenSecretItemKey]
+	if tokenSecretName != "" && tokenSecretItemKey == "" {
+		allErrs = append(allErrs, field.Required(sparkConfPath.Key(kerberosTokenSecretItemKey), fmt.Sprintf("must be set with %s", kerberosTokenSecretNameKey)))
+	}
+	if tokenSecretItemKey != "" && tokenSecretName == "" {
+		allErrs = append(allErrs, field.Required(sparkConfPath.Key(kerberosTokenSecretNameKey), fmt.Sprintf("must be set with %s", kerberosTokenSecretItemKey)))
+	}
+	if sparkConf[kerberosKeytabKey] != "" && sparkConf[kerberosPrincipalKey] == "" {
+		allErrs = append(allErrs, field.Required(sparkConfPath.Key(kerberosPrincipalKey), fmt.Sprintf("must be set with %s", kerberosKeytabKey)))
+	}
+	return allErrs
+}
+
+// validateSecretKeyRefs Helper func to check the env vars taken from secret keys, given in sparkConf as
+// secretKeyRef.<env>=<secret>:<key> or in envSecretKeyRefs, name a valid secret and key
+func validateSecretKeyRefs(app *v1beta2.SparkApplication, specPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	sparkConfPath := specPath.Child("sparkConf")
+	for key, value := range app.Spec.SparkConf {
+		envName, found := strings.CutPrefix(key, common.SparkDriverSecretKeyRefKeyPrefix)
+		if !found {
+			envName, found = strings.CutPrefix(key, executorSecretKeyRefPrefix)
+		}
+		if !found {
+			continue
+		}
+		keyPath := sparkConfPath.Key(key)
+		for _, msg := range validation.IsEnvVarName(envName) {
+			allErrs = append(allErrs, field.Invalid(keyPath, envName, msg))
+		}
+		secretName, secretKey, separated := strings.Cut(value, ":")
+		if !separated || secretName == "" || secretKey == "" || strings.Contains(secretKey, ":") {
+			allErrs = append(allErrs, field.Invalid(keyPath, value, "must be in the form <secret name>:<key>"))
+			continue
+		}
+		allErrs = append(allErrs, validateSecretKeySelector(secretName, secretKey, keyPath, keyPath)...)
+	}
+	for _, podSpec := range []struct {
+		envSecretKeyRefs map[string]v1beta2.NameKey
+		path             *field.Path
+	}{
+		{app.Spec.Driver.EnvSecretKeyRefs, specPath.Child("driver", "envSecretKeyRefs")},
+		{app.Spec.Executor.EnvSecretKeyRefs, specPath.Child("executor", "envSecretKeyRefs")},
+	} {
+		for envName, nameKey := range podSpec.envSecretKeyRefs {
+			envPath := podSpec.path.Key(envName)
+			for _, msg := range validation.IsEnvVarName(envName) {
+				allErrs = append(allErrs, field.Invalid(envPath, envName, msg))
+			}
+			allErrs = append(allErrs, validateSecretKeySelector(nameKey.Name, nameKey.Key, envPath.Child("name"), envPath.Child("key"))...)
+		}
+	}
+	return sortErrors(allErrs)
+}
+
+// Helper func to check the secret name and key an env var is taken from
+func validateSecretKeySelector(secretName string, secretKey string, namePath *field.Path, keyPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if secretName == "" {
+		allErrs = append(allErrs, field.Required(namePath, ""))
+	} else {
+		for _, msg := range validation.IsDNS1123Subdomain(secretName) {
+			allErrs = append(allErrs, field.Invalid(namePath, secretName, msg))
+		}
+	}
+	if secretKey == "" {
+		allErrs = append(allErrs, field.Required(keyPath, ""))
+	} else {
+		for _, msg := range validation.IsConfigMapKey(secretKey) {
+			allErrs = append(allErrs, field.Invalid(keyPath, secretKey, msg))
+		}
+	}
+	return allErrs
+}
+
+// sortErrors Helper func to order errors collected from maps by field path, so the reported list is stable
+func sortErrors(allErrs field.ErrorList) field.ErrorList {
+	sort.SliceStable(allErrs, func(i, j int) bool {
+		return allErrs[i].Field < allErrs[j].Field
+	})
+	return allErrs
+}
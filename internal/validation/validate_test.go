@@ -0,0 +1,305 @@
+package validation
+
+import (
+	"nativesubmit/common"
+	"testing"
+
+	"github.com/kubeflow/spark-operator/api/v1beta2"
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func validApp() *v1beta2.SparkApplication {
+	return &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-app",
+			Namespace: "default",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Image: common.StringPointer("spark:3.5.0"),
+			Driver: v1beta2.DriverSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					Cores:        common.Int32Pointer(1),
+					Memory:       common.StringPointer("512m"),
+					CoreLimit:    common.StringPointer("1200m"),
+					Labels:       map[string]string{"version": "3.5.0"},
+					VolumeMounts: []apiv1.VolumeMount{{Name: "data", MountPath: "/data"}},
+				},
+			},
+			Executor: v1beta2.ExecutorSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					Memory: common.StringPointer("2g"),
+				},
+			},
+			Volumes: []apiv1.Volume{{Name: "data"}},
+			SparkConf: map[string]string{
+				"spark.driver.port":                     "7078",
+				"spark.kubernetes.driver.label.team":    "data",
+				"spark.kubernetes.driver.request.cores": "500m",
+			},
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		mutate     func(app *v1beta2.SparkApplication)
+		wantFields []string
+	}{
+		{
+			name:   "valid application",
+			mutate: func(app *v1beta2.SparkApplication) {},
+		},
+		{
+			name:       "missing name",
+			mutate:     func(app *v1beta2.SparkApplication) { app.Name = "" },
+			wantFields: []string{"metadata.name"},
+		},
+		{
+			name:       "invalid driver pod name",
+			mutate:     func(app *v1beta2.SparkApplication) { app.Spec.Driver.PodName = common.StringPointer("Driver_Pod") },
+			wantFields: []string{"spec.driver.podName"},
+		},
+		{
+			name: "invalid driver pod name from sparkConf",
+			mutate: func(app *v1beta2.SparkApplication) {
+				app.Spec.SparkConf[common.SparkDriverPodNameKey] = "Driver_Pod"
+			},
+			wantFields: []string{"spec.sparkConf[spark.kubernetes.driver.pod.name]"},
+		},
+		{
+			name: "missing image",
+			mutate: func(app *v1beta2.SparkApplication) {
+				app.Spec.Image = nil
+			},
+			wantFields: []string{"spec.image"},
+		},
+		{
+			name: "image from sparkConf",
+			mutate: func(app *v1beta2.SparkApplication) {
+				app.Spec.Image = nil
+				app.Spec.SparkConf["spark.kubernetes.container.image"] = "spark:3.5.0"
+			},
+		},
+		{
+			name: "invalid ports",
+			mutate: func(app *v1beta2.SparkApplication) {
+				app.Spec.SparkConf["spark.driver.port"] = "http"
+				app.Spec.SparkConf["spark.blockManager.port"] = "70000"
+				app.Spec.Driver.Ports = []v1beta2.Port{{Name: "metrics", ContainerPort: 0}}
+			},
+			wantFields: []string{
+				"spec.sparkConf[spark.driver.port]",
+				"spec.sparkConf[spark.blockManager.port]",
+				"spec.driver.ports[0].containerPort",
+			},
+		},
+		{
+			name: "random spark ports",
+			mutate: func(app *v1beta2.SparkApplication) {
+				app.Spec.SparkConf["spark.driver.port"] = "0"
+				app.Spec.SparkConf["spark.blockManager.port"] = "0"
+				app.Spec.Driver.HostNetwork = common.BoolPointer(true)
+			},
+		},
+		{
+			name: "unsupported memory unit",
+			mutate: func(app *v1beta2.SparkApplication) {
+				app.Spec.Executor.Memory = common.StringPointer("1p")
+			},
+			wantFields: []string{"spec.executor.memory"},
+		},
+		{
+			name: "invalid memory",
+			mutate: func(app *v1beta2.SparkApplication) {
+				app.Spec.Driver.Memory = common.StringPointer("512 MiB")
+				app.Spec.SparkConf["spark.executor.memoryOverhead"] = "lots"
+				app.Spec.MemoryOverheadFactor = common.StringPointer("-0.1")
+			},
+			wantFields: []string{
+				"spec.driver.memory",
+				"spec.sparkConf[spark.executor.memoryOverhead]",
+				"spec.memoryOverheadFactor",
+			},
+		},
+		{
+			name: "invalid cpu",
+			mutate: func(app *v1beta2.SparkApplication) {
+				app.Spec.Driver.Cores = common.Int32Pointer(0)
+				app.Spec.Driver.CoreLimit = common.StringPointer("one")
+				app.Spec.SparkConf["spark.driver.cores"] = "1.5"
+				app.Spec.SparkConf["spark.kubernetes.driver.request.cores"] = "half"
+			},
+			wantFields: []string{
+				"spec.driver.cores",
+				"spec.sparkConf[spark.driver.cores]",
+				"spec.driver.coreLimit",
+				"spec.sparkConf[spark.kubernetes.driver.request.cores]",
+			},
+		},
+		{
+			name: "invalid labels and annotations",
+			mutate: func(app *v1beta2.SparkApplication) {
+				app.Spec.Driver.Labels["bad key"] = "value"
+				app.Spec.Executor.Annotations = map[string]string{"/empty-prefix": "value"}
+				app.Spec.SparkConf["spark.kubernetes.driver.label.team"] = "not a valid value"
+			},
+			wantFields: []string{
+				"spec.driver.labels",
+				"spec.executor.annotations",
+				"spec.sparkConf[spark.kubernetes.driver.label.team]",
+			},
+		},
+		{
+			name: "invalid volume references",
+			mutate: func(app *v1beta2.SparkApplication) {
+				app.Spec.Volumes = append(app.Spec.Volumes, apiv1.Volume{Name: "data"}, apiv1.Volume{Name: "Scratch"})
+				app.Spec.Driver.VolumeMounts = append(app.Spec.Driver.VolumeMounts, apiv1.VolumeMount{Name: "missing"})
+			},
+			wantFields: []string{
+				"spec.volumes[1].name",
+				"spec.volumes[2].name",
+				"spec.driver.volumeMounts[1].name",
+				"spec.driver.volumeMounts[1].mountPath",
+			},
+		},
+		{
+			name: "invalid driver scheduling",
+			mutate: func(app *v1beta2.SparkApplication) {
+				app.Spec.Driver.PriorityClassName = common.StringPointer("High_Priority")
+				app.Spec.Driver.Template = &apiv1.PodTemplateSpec{Spec: apiv1.PodSpec{
+					SchedulingGates:           []apiv1.PodSchedulingGate{{Name: "example.com/quota"}, {Name: "bad gate"}},
+					TopologySpreadConstraints: []apiv1.TopologySpreadConstraint{{MaxSkew: 0, WhenUnsatisfiable: "Sometimes"}},
+				}}
+			},
+			wantFields: []string{
+				"spec.driver.priorityClassName",
+				"spec.driver.template.spec.schedulingGates[1].name",
+				"spec.driver.template.spec.topologySpreadConstraints[0].maxSkew",
+				"spec.driver.template.spec.topologySpreadConstraints[0].topologyKey",
+				"spec.driver.template.spec.topologySpreadConstraints[0].whenUnsatisfiable",
+			},
+		},
+		{
+			name: "invalid config maps",
+			mutate: func(app *v1beta2.SparkApplication) {
+				app.Spec.Volumes = append(app.Spec.Volumes, apiv1.Volume{Name: "log4j-vol"})
+				app.Spec.Driver.ConfigMaps = []v1beta2.NamePath{
+					{Name: "log4j", Path: "/etc/log4j"},
+					{Name: "metrics", Path: "/opt/spark/conf/"},
+					{Name: "hive", Path: "/opt/spark/conf/hive"},
+					{Name: "Hadoop_Conf", Path: "/etc/hadoop"},
+					{Name: "metrics", Path: "/etc/hadoop/"},
+				}
+				app.Spec.Executor.ConfigMaps = []v1beta2.NamePath{{Name: "executor-metrics"}}
+				app.Spec.SparkConfigMap = common.StringPointer("spark_conf")
+				app.Spec.HadoopConfigMap = common.StringPointer("hadoop-conf")
+			},
+			wantFields: []string{
+				"spec.sparkConfigMap",
+				"spec.driver.configMaps[0].name",
+				"spec.driver.configMaps[1].path",
+				"spec.driver.configMaps[2].path",
+				"spec.driver.configMaps[3].name",
+				"spec.driver.configMaps[4].name",
+				"spec.driver.configMaps[4].path",
+				"spec.executor.configMaps[0].path",
+			},
+		},
+		{
+			name: "invalid kerberos and credential properties",
+			mutate: func(app *v1beta2.SparkApplication) {
+				app.Spec.SparkConf["spark.kubernetes.kerberos.krb5.path"] = "/etc/krb5.conf"
+				app.Spec.SparkConf["spark.kubernetes.kerberos.krb5.configMapName"] = "krb5"
+				app.Spec.SparkConf["spark.kubernetes.kerberos.tokenSecret.name"] = "Hadoop_Tokens"
+				app.Spec.SparkConf["spark.kerberos.keytab"] = "/etc/security/spark.keytab"
+				app.Spec.SparkConf["spark.kubernetes.authenticate.driver.oauthToken"] = "token"
+				app.Spec.SparkConf["spark.kubernetes.authenticate.driver.oauthTokenFile"] = "/var/run/token"
+			},
+			wantFields: []string{
+				"spec.sparkConf[spark.kubernetes.authenticate.driver.oauthTokenFile]",
+				"spec.sparkConf[spark.kubernetes.kerberos.krb5.configMapName]",
+				"spec.sparkConf[spark.kubernetes.kerberos.tokenSecret.name]",
+				"spec.sparkConf[spark.kubernetes.kerberos.tokenSecret.itemKey]",
+				"spec.sparkConf[spark.kerberos.principal]",
+			},
+		},
+		{
+			name: "invalid secret key refs",
+			mutate: func(app *v1beta2.SparkApplication) {
+				app.Spec.SparkConf["spark.kubernetes.driver.secretKeyRef.DB_PASSWORD"] = "db-credentials:password"
+				app.Spec.SparkConf["spark.kubernetes.driver.secretKeyRef.API_TOKEN"] = "api-token"
+				app.Spec.SparkConf["spark.kubernetes.driver.secretKeyRef.1TOKEN"] = "tokens:first"
+				app.Spec.SparkConf["spark.kubernetes.executor.secretKeyRef.S3_KEY"] = "S3_Credentials:key"
+				app.Spec.Driver.EnvSecretKeyRefs = map[string]v1beta2.NameKey{
+					"DB_USER":     {Name: "db-credentials", Key: "user"},
+					"DB_HOST":     {Name: "db-credentials"},
+					"DB_PASSWORD": {Name: "db-credentials", Key: "pass word"},
+				}
+			},
+			wantFields: []string{
+				"spec.sparkConf[spark.kubernetes.driver.secretKeyRef.API_TOKEN]",
+				"spec.sparkConf[spark.kubernetes.driver.secretKeyRef.1TOKEN]",
+				"spec.sparkConf[spark.kubernetes.executor.secretKeyRef.S3_KEY]",
+				"spec.driver.envSecretKeyRefs[DB_HOST].key",
+				"spec.driver.envSecretKeyRefs[DB_PASSWORD].key",
+			},
+		},
+		{
+			name: "host network port conflicts",
+			mutate: func(app *v1beta2.SparkApplication) {
+				app.Spec.Driver.HostNetwork = common.BoolPointer(true)
+				app.Spec.SparkConf["spark.driver.port"] = "4040"
+				app.Spec.Driver.Sidecars = []apiv1.Container{{
+					Name: "proxy",
+					Ports: []apiv1.ContainerPort{
+						{ContainerPort: 7079},
+						{ContainerPort: 7079, Protocol: apiv1.ProtocolUDP},
+						{ContainerPort: 9090},
+						{ContainerPort: 9090},
+					},
+				}}
+			},
+			wantFields: []string{
+				"spec.sparkConf[spark.driver.port]",
+				"spec.driver.sidecars[0].ports[0].containerPort",
+				"spec.driver.sidecars[0].ports[3].containerPort",
+			},
+		},
+		{
+			name: "sidecar ports without host network",
+			mutate: func(app *v1beta2.SparkApplication) {
+				app.Spec.Driver.Sidecars = []apiv1.Container{{Name: "proxy", Ports: []apiv1.ContainerPort{{ContainerPort: 4040}}}}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := validApp()
+			tt.mutate(app)
+			errs := Validate(app)
+			assert.ElementsMatch(t, tt.wantFields, errorFields(errs), errs.ToAggregate())
+		})
+	}
+}
+
+func TestValidateNilApplication(t *testing.T) {
+	assert.Len(t, Validate(nil), 1)
+}
+
+// errorFields Helper func to collect the distinct field paths of an error list
+func errorFields(errs field.ErrorList) []string {
+	var fields []string
+	seen := make(map[string]bool)
+	for _, err := range errs {
+		if !seen[err.Field] {
+			seen[err.Field] = true
+			fields = append(fields, err.Field)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,482 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"nativesubmit/common"
+	"nativesubmit/internal/configmap"
+	"nativesubmit/internal/driver"
+	"nativesubmit/internal/events"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/kubeflow/spark-operator/api/v1beta2"
+	apiv1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	cliName         = "native-submit"
+	renderCommand   = "render"
+	submitCommand   = "submit"
+	diffCommand     = "diff"
+	stdinFileName   = "-"
+	yamlDocumentSep = "---\n"
+	// Exit codes follow the kubectl diff convention: 1 means differences were found, anything above means an error
+	exitCodeOK          = 0
+	exitCodeDifferences = 1
+	exitCodeError       = 2
+	// eventsFlushTimeout bounds how long submit waits for its events to be written before exiting
+	eventsFlushTimeout = 10 * time.Second
+)
+
+const cliUsage = `Usage: native-submit <command> [flags]
+
+Commands:
+  render   Print the ConfigMap, driver Pod and Service rendered for a SparkApplication as YAML
+  submit   Create the rendered resources in the cluster, for a SparkApplication that already exists there
+  diff     Compare the rendered resources with the live ones in the cluster
+
+Run 'native-submit <command> -h' for the flags of a command.
+`
+
+// cliOptions holds the flags shared by the native-submit subcommands
+type cliOptions struct {
+	fileName      string
+	kubeconfig    string
+	kubeContext   string
+	submissionID  string
+	keepOnFailure bool
+	timeout       time.Duration
+	gracePeriod   int64
+	takeover      bool
+	serverSide    bool
+}
+
+// runCLI runs the native-submit command line tool and returns the process exit code
+func runCLI(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, cliUsage)
+		return exitCodeError
+	}
+
+	command := args[0]
+	var opts cliOptions
+	flags := flag.NewFlagSet(cliName+" "+command, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&opts.fileName, "f", stdinFileName, "SparkApplication YAML file, '-' reads from stdin")
+	switch command {
+	case renderCommand:
+	case submitCommand, diffCommand:
+		flags.StringVar(&opts.kubeconfig, "kubeconfig", "", "path to the kubeconfig file, defaults to the standard loading rules")
+		flags.StringVar(&opts.kubeContext, "context", "", "kubeconfig context to use")
+		if command == submitCommand {
+			flags.StringVar(&opts.submissionID, "submission-id", "", "submission ID to use, a new one is generated when empty")
+			flags.BoolVar(&opts.keepOnFailure, "keep-on-failure", false, "leave the resources of a failed submission in place for debugging")
+			flags.DurationVar(&opts.timeout, "timeout", 0, "overall submission deadline, zero means none")
+			flags.BoolVar(&opts.serverSide, "server-side", false, "write the resources with server-side apply, preserving fields owned by other managers")
+			flags.BoolVar(&opts.takeover, "takeover", false, "adopt existing resources owned by a different SparkApplication instead of failing")
+			flags.Int64Var(&opts.gracePeriod, "grace-period", -1, "seconds given to the driver pod of a previous submission to terminate, negative uses the pod's own")
+			flags.Usage = func() {
+				fmt.Fprintf(stderr, "Usage: %s %s [flags]\n\n", cliName, submitCommand)
+				fmt.Fprint(stderr, "The SparkApplication must already exist in the cluster, as the created resources are owned by it.\n\n")
+				flags.PrintDefaults()
+			}
+		}
+	case "-h", "--help", "help":
+		fmt.Fprint(stdout, cliUsage)
+		return exitCodeOK
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", command, cliUsage)
+		return exitCodeError
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return exitCodeOK
+		}
+		return exitCodeError
+	}
+
+	app, err := readSparkApplication(opts.fileName, stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitCodeError
+	}
+
+	if command == renderCommand {
+		if err := runRender(app, stdout); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitCodeError
+		}
+		return exitCodeOK
+	}
+
+	kubeClient, err := newKubeClient(opts.kubeconfig, opts.kubeContext)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitCodeError
+	}
+
+	ctx := context.Background()
+	if command == submitCommand {
+		nativeSubmit := &NativeSubmit{KeepResourcesOnFailure: opts.keepOnFailure, SubmissionTimeout: opts.timeout, AllowOwnershipTakeover: opts.takeover}
+		if opts.serverSide {
+			nativeSubmit.ApplyMode = common.ApplyModeServerSide
+		}
+		if opts.gracePeriod >= 0 {
+			nativeSubmit.DriverPodDeletionGracePeriodSeconds = &opts.gracePeriod
+		}
+		// The events are written in the background, so the process waits for them before it exits
+		recorder := events.NewRecorder(ctx, kubeClient, logr.Discard())
+		nativeSubmit.EventRecorder = recorder
+		err := runSubmit(ctx, nativeSubmit, app, opts.submissionID, kubeClient, stdout)
+		recorder.Close()
+		flushCtx, cancel := context.WithTimeout(ctx, eventsFlushTimeout)
+		defer cancel()
+		_ = recorder.Wait(flushCtx)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitCodeError
+		}
+		return exitCodeOK
+	}
+
+	differences, err := runDiff(ctx, app, kubeClient, stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitCodeError
+	}
+	if differences {
+		return exitCodeDifferences
+	}
+	return exitCodeOK
+}
+
+// readSparkApplication decodes a SparkApplication from the named YAML file or from stdin
+func readSparkApplication(fileName string, stdin io.Reader) (*v1beta2.SparkApplication, error) {
+	var data []byte
+	var err error
+	if fileName == stdinFileName {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(fileName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spark application: %w", err)
+	}
+
+	app := &v1beta2.SparkApplication{}
+	if err := yaml.UnmarshalStrict(data, app); err != nil {
+		return nil, fmt.Errorf("failed to decode spark application: %w", err)
+	}
+	if app.Kind != "" && app.Kind != "SparkApplication" {
+		return nil, fmt.Errorf("expected kind SparkApplication, got %s", app.Kind)
+	}
+	if app.Name == "" {
+		return nil, fmt.Errorf("spark application name cannot be empty")
+	}
+	if app.Namespace == "" {
+		app.Namespace = "default"
+	}
+	return app, nil
+}
+
+// newKubeClient builds a client for the cluster selected by the kubeconfig and context. It is a variable so tests can
+// run the commands against a fake client.
+var newKubeClient = func(kubeconfig string, kubeContext string) (ctrlClient.Client, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	if err := v1beta2.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	return ctrlClient.New(restConfig, ctrlClient.Options{Scheme: scheme})
+}
+
+// runRender prints the rendered resources as a multi-document YAML stream, with the contents of Secrets redacted
+func runRender(app *v1beta2.SparkApplication, out io.Writer) error {
+	rendered, err := (&NativeSubmit{}).RenderSparkApplication(app)
+	if err != nil {
+		return err
+	}
+	for _, obj := range rendered.Objects() {
+		redactSecret(obj)
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", obj.GetName(), err)
+		}
+		fmt.Fprint(out, yamlDocumentSep)
+		if _, err := out.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runSubmit creates the rendered resources for a SparkApplication that already exists in the cluster.
+// The live object is required so the created resources carry a valid owner reference.
+func runSubmit(ctx context.Context, nativeSubmit *NativeSubmit, app *v1beta2.SparkApplication, submissionID string, kubeClient ctrlClient.Client, out io.Writer) error {
+	liveApp := &v1beta2.SparkApplication{}
+	if err := kubeClient.Get(ctx, ctrlClient.ObjectKeyFromObject(app), liveApp); err != nil {
+		if apiErrors.IsNotFound(err) {
+			return fmt.Errorf("spark application %s in namespace %s must exist in the cluster before it is submitted: %w", app.Name, app.Namespace, err)
+		}
+		return fmt.Errorf("failed to get spark application %s in namespace %s: %w", app.Name, app.Namespace, err)
+	}
+	app.UID = liveApp.UID
+
+	if submissionID == "" {
+		submissionID = uuid.New().String()
+	}
+	result, err := nativeSubmit.runAltSparkSubmit(ctx, app, submissionID, kubeClient)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "submitted spark application %s in namespace %s: submission ID %s, spark application ID %s\n",
+		app.Name, app.Namespace, result.SubmissionID, result.SparkApplicationID)
+	fmt.Fprintf(out, "configmap/%s %s\n", result.ConfigMapName, result.Operations[PhaseConfigMap])
+	fmt.Fprintf(out, "pod/%s %s\n", result.DriverPodName, result.Operations[PhaseDriverPod])
+	fmt.Fprintf(out, "service/%s %s\n", result.ServiceName, result.Operations[PhaseService])
+	return nil
+}
+
+// runDiff prints the differences between the rendered resources and the live ones and reports whether any were found.
+// Only the fields native submit renders are compared, so values defaulted by the API server do not show up. The
+// contents of Secrets are redacted on both sides, so only their keys are compared.
+func runDiff(ctx context.Context, app *v1beta2.SparkApplication, kubeClient ctrlClient.Client, out io.Writer) (bool, error) {
+	// Owner references of the live resources point at the live SparkApplication
+	liveApp := &v1beta2.SparkApplication{}
+	if err := kubeClient.Get(ctx, ctrlClient.ObjectKeyFromObject(app), liveApp); err == nil {
+		app.UID = liveApp.UID
+		if app.Status.SubmissionID == "" {
+			app.Status.SubmissionID = liveApp.Status.SubmissionID
+		}
+	} else if !apiErrors.IsNotFound(err) {
+		return false, fmt.Errorf("failed to get spark application %s in namespace %s: %w", app.Name, app.Namespace, err)
+	}
+	if app.Status.SubmissionID == "" {
+		// The status is not written by submit, the live resources carry the submission ID instead
+		submissionID, err := liveSubmissionID(ctx, app, kubeClient)
+		if err != nil {
+			return false, err
+		}
+		app.Status.SubmissionID = submissionID
+	}
+
+	// Render with the identifiers of the live submission so generated IDs do not show up as differences
+	identity, err := resolveSubmissionIdentity(ctx, app, app.Status.SubmissionID, kubeClient, false)
+	if apiErrors.IsConflict(err) {
+		identity = submissionIdentity{SubmissionID: app.Status.SubmissionID}
+	} else if err != nil {
+		return false, err
+	}
+	rendered, err := renderResources(ctx, app.DeepCopy(), identity, (&NativeSubmit{}).featureSteps(), true)
+	if err != nil {
+		return false, err
+	}
+	if err := configmap.MergeSparkConfigMap(ctx, kubeClient, app, rendered.ConfigMap); err != nil {
+		return false, err
+	}
+
+	differences := false
+	for _, obj := range rendered.Objects() {
+		kind := obj.GetObjectKind().GroupVersionKind().Kind
+		if kind == "" {
+			kind = reflect.TypeOf(obj).Elem().Name()
+		}
+		live := obj.DeepCopyObject().(ctrlClient.Object)
+		if err := kubeClient.Get(ctx, ctrlClient.ObjectKeyFromObject(obj), live); err != nil {
+			if apiErrors.IsNotFound(err) {
+				fmt.Fprintf(out, "%s %s: not found in namespace %s\n", kind, obj.GetName(), obj.GetNamespace())
+				differences = true
+				continue
+			}
+			return false, fmt.Errorf("failed to get %s %s in namespace %s: %w", kind, obj.GetName(), obj.GetNamespace(), err)
+		}
+		redactSecret(obj)
+		redactSecret(live)
+		adoptGeneratedValues(obj, live)
+
+		renderedFields, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return false, err
+		}
+		// Set by the API server only
+		unstructured.RemoveNestedField(renderedFields, "metadata", "creationTimestamp")
+		liveFields, err := runtime.DefaultUnstructuredConverter.ToUnstructured(live)
+		if err != nil {
+			return false, err
+		}
+		if diff := cmp.Diff(pruneToRendered(liveFields, renderedFields), renderedFields); diff != "" {
+			fmt.Fprintf(out, "%s %s (-live +rendered):\n%s\n", kind, obj.GetName(), diff)
+			differences = true
+		}
+	}
+	return differences, nil
+}
+
+// liveSubmissionID Helper func to get the submission ID of the live driver pod, or of the live ConfigMap when there is no
+// driver pod. It is empty when neither exists.
+func liveSubmissionID(ctx context.Context, app *v1beta2.SparkApplication, kubeClient ctrlClient.Client) (string, error) {
+	driverPodName := common.GetDriverPodName(app)
+	candidates := []struct {
+		name string
+		obj  ctrlClient.Object
+	}{
+		{name: driverPodName, obj: &apiv1.Pod{}},
+		{name: driverPodName + ConfigMapExtension, obj: &apiv1.ConfigMap{}},
+	}
+	for _, candidate := range candidates {
+		key := ctrlClient.ObjectKey{Namespace: app.Namespace, Name: candidate.name}
+		if err := kubeClient.Get(ctx, key, candidate.obj); err != nil {
+			if apiErrors.IsNotFound(err) {
+				continue
+			}
+			return "", fmt.Errorf("failed to get %s in namespace %s: %w", key.Name, key.Namespace, err)
+		}
+		if submissionID := candidate.obj.GetLabels()[SparkAppSubmissionIDAnnotation]; submissionID != "" {
+			return submissionID, nil
+		}
+	}
+	return "", nil
+}
+
+// adoptGeneratedValues copies the values generated anew on every render from the live object into the rendered one:
+// the submit time of spark.properties and the default local dir of the driver container
+func adoptGeneratedValues(rendered ctrlClient.Object, live ctrlClient.Object) {
+	switch renderedObj := rendered.(type) {
+	case *apiv1.ConfigMap:
+		liveConfigMap := live.(*apiv1.ConfigMap)
+		submitTimePrefix := configmap.SparkApplicationSubmitTime + "="
+		var liveSubmitTime string
+		for _, line := range strings.Split(liveConfigMap.Data[configmap.SparkPropertiesFileName], configmap.NewLineString) {
+			if strings.HasPrefix(line, submitTimePrefix) {
+				liveSubmitTime = line
+			}
+		}
+		if liveSubmitTime == "" {
+			return
+		}
+		lines := strings.Split(renderedObj.Data[configmap.SparkPropertiesFileName], configmap.NewLineString)
+		for index, line := range lines {
+			if strings.HasPrefix(line, submitTimePrefix) {
+				lines[index] = liveSubmitTime
+			}
+		}
+		renderedObj.Data[configmap.SparkPropertiesFileName] = strings.Join(lines, configmap.NewLineString)
+	case *apiv1.Pod:
+		livePod := live.(*apiv1.Pod)
+		renderedContainer := driverContainer(renderedObj)
+		liveContainer := driverContainer(livePod)
+		if renderedContainer == nil || liveContainer == nil {
+			return
+		}
+		renderedLocalDir := envValue(renderedContainer, driver.SparkLocalDir)
+		liveLocalDir := envValue(liveContainer, driver.SparkLocalDir)
+		if !strings.HasPrefix(renderedLocalDir, driver.SparkLocalDirPath) || !strings.HasPrefix(liveLocalDir, driver.SparkLocalDirPath) {
+			return
+		}
+		for index := range renderedContainer.Env {
+			if renderedContainer.Env[index].Name == driver.SparkLocalDir {
+				renderedContainer.Env[index].Value = liveLocalDir
+			}
+		}
+		for index := range renderedContainer.VolumeMounts {
+			if renderedContainer.VolumeMounts[index].MountPath == renderedLocalDir {
+				renderedContainer.VolumeMounts[index].MountPath = liveLocalDir
+			}
+		}
+	}
+}
+
+// driverContainer Helper func to get the Spark driver container of a driver pod
+func driverContainer(pod *apiv1.Pod) *apiv1.Container {
+	for index := range pod.Spec.Containers {
+		if pod.Spec.Containers[index].Name == common.SparkDriverContainerName {
+			return &pod.Spec.Containers[index]
+		}
+	}
+	return nil
+}
+
+// envValue Helper func to get the literal value of the last env var of a container with the given name
+func envValue(container *apiv1.Container, name string) string {
+	var value string
+	for _, envVar := range container.Env {
+		if envVar.Name == name {
+			value = envVar.Value
+		}
+	}
+	return value
+}
+
+// redactSecret replaces the values of a Secret with common.RedactedValue, so they are not printed. Other objects are
+// left unchanged.
+func redactSecret(obj ctrlClient.Object) {
+	secret, ok := obj.(*apiv1.Secret)
+	if !ok {
+		return
+	}
+	for key := range secret.Data {
+		secret.Data[key] = []byte(common.RedactedValue)
+	}
+	for key := range secret.StringData {
+		secret.StringData[key] = common.RedactedValue
+	}
+}
+
+// pruneToRendered drops every field of the live object that is absent from the rendered one
+func pruneToRendered(live interface{}, rendered interface{}) interface{} {
+	switch renderedValue := rendered.(type) {
+	case map[string]interface{}:
+		liveValue, ok := live.(map[string]interface{})
+		if !ok {
+			return live
+		}
+		pruned := make(map[string]interface{}, len(renderedValue))
+		for key, value := range renderedValue {
+			if liveField, exists := liveValue[key]; exists {
+				pruned[key] = pruneToRendered(liveField, value)
+			}
+		}
+		return pruned
+	case []interface{}:
+		liveValue, ok := live.([]interface{})
+		if !ok {
+			return live
+		}
+		pruned := make([]interface{}, len(liveValue))
+		for index, item := range liveValue {
+			if index < len(renderedValue) {
+				pruned[index] = pruneToRendered(item, renderedValue[index])
+			} else {
+				pruned[index] = item
+			}
+		}
+		return pruned
+	default:
+		return live
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"nativesubmit/internal/driver"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kubeflow/spark-operator/api/v1beta2"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testSparkApplicationYAML = `apiVersion: sparkoperator.k8s.io/v1beta2
+kind: SparkApplication
+metadata:
+  name: spark-pi
+  namespace: spark-jobs
+spec:
+  type: Scala
+  mode: cluster
+  image: spark:3.5.0
+  mainClass: org.apache.spark.examples.SparkPi
+  mainApplicationFile: local:///opt/spark/examples/jars/spark-examples.jar
+  sparkVersion: 3.5.0
+  driver:
+    cores: 1
+    memory: 512m
+  executor:
+    instances: 1
+    cores: 1
+    memory: 512m
+`
+
+func TestRunCLI(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		stdin    string
+		wantCode int
+		wantOut  []string
+	}{
+		{
+			name:     "render from stdin",
+			args:     []string{"render"},
+			stdin:    testSparkApplicationYAML,
+			wantCode: exitCodeOK,
+			wantOut: []string{
+				"kind: ConfigMap",
+				"name: spark-pi-driver-conf-map",
+				"kind: Pod",
+				"name: spark-pi-driver",
+				"kind: Service",
+				"name: spark-pi-driver-svc",
+			},
+		},
+		{
+			name:     "no command",
+			args:     nil,
+			wantCode: exitCodeError,
+		},
+		{
+			name:     "unknown command",
+			args:     []string{"apply"},
+			wantCode: exitCodeError,
+		},
+		{
+			name:     "invalid spark application",
+			args:     []string{"render", "-f", "-"},
+			stdin:    "kind: Pod\nmetadata:\n  name: not-an-app\n",
+			wantCode: exitCodeError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := runCLI(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+			assert.Equal(t, tt.wantCode, code, stderr.String())
+			for _, want := range tt.wantOut {
+				assert.Contains(t, stdout.String(), want)
+			}
+		})
+	}
+}
+
+func TestReadSparkApplication(t *testing.T) {
+	app, err := readSparkApplication("-", strings.NewReader(testSparkApplicationYAML))
+	assert.NoError(t, err)
+	assert.Equal(t, "spark-pi", app.Name)
+	assert.Equal(t, "spark-jobs", app.Namespace)
+
+	app, err = readSparkApplication("-", strings.NewReader("metadata:\n  name: spark-pi\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "default", app.Namespace)
+
+	_, err = readSparkApplication("-", strings.NewReader("metadata:\n  namespace: spark-jobs\n"))
+	assert.Error(t, err)
+
+	_, err = readSparkApplication("/does/not/exist.yaml", nil)
+	assert.Error(t, err)
+}
+
+func TestRunDiff(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = v1beta2.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	app, err := readSparkApplication("-", strings.NewReader(testSparkApplicationYAML))
+	assert.NoError(t, err)
+
+	cl := fake.NewClientBuilder().WithScheme(scheme).Build()
+	var out bytes.Buffer
+	differences, err := runDiff(context.TODO(), app, cl, &out)
+	assert.NoError(t, err)
+	assert.True(t, differences)
+	assert.Contains(t, out.String(), "ConfigMap spark-pi-driver-conf-map: not found")
+	assert.Contains(t, out.String(), "Pod spark-pi-driver: not found")
+	assert.Contains(t, out.String(), "Service spark-pi-driver-svc: not found")
+}
+
+// newCredentialsTestSparkApplication Helper func to get the test Spark Application with Kubernetes credentials and a
+// keytab shipped from the submission host, and the secret contents it ships
+func newCredentialsTestSparkApplication(t *testing.T) (*v1beta2.SparkApplication, []string) {
+	dir := t.TempDir()
+	clientKeyPath := filepath.Join(dir, "client.key")
+	assert.NoError(t, os.WriteFile(clientKeyPath, []byte("client-key-bytes"), 0o600))
+	keytabPath := filepath.Join(dir, "spark.keytab")
+	assert.NoError(t, os.WriteFile(keytabPath, []byte("keytab-bytes"), 0o600))
+
+	app, err := readSparkApplication("-", strings.NewReader(testSparkApplicationYAML))
+	assert.NoError(t, err)
+	app.Spec.SparkConf = map[string]string{
+		driver.OAuthToken:        "oauth-token-bytes",
+		driver.ClientKeyFile:     clientKeyPath,
+		driver.KerberosKeytab:    keytabPath,
+		driver.KerberosPrincipal: "spark@EXAMPLE.COM",
+	}
+	var secrets []string
+	for _, secret := range []string{"oauth-token-bytes", "client-key-bytes", "keytab-bytes"} {
+		secrets = append(secrets, secret, base64.StdEncoding.EncodeToString([]byte(secret)))
+	}
+	return app, secrets
+}
+
+func TestRunRenderRedactsSecrets(t *testing.T) {
+	app, secrets := newCredentialsTestSparkApplication(t)
+	var out bytes.Buffer
+	assert.NoError(t, runRender(app, &out))
+	assert.Contains(t, out.String(), "name: spark-pi-driver-kubernetes-credentials")
+	assert.Contains(t, out.String(), "name: spark-pi-driver-kerberos-keytab")
+	for _, secret := range secrets {
+		assert.NotContains(t, out.String(), secret)
+	}
+}
+
+func TestRunDiffRedactsSecrets(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = v1beta2.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	app, secrets := newCredentialsTestSparkApplication(t)
+	liveSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "spark-pi-driver-kubernetes-credentials", Namespace: "spark-jobs"},
+		Data:       map[string][]byte{"oauth-token": []byte("live-token-bytes"), "client-key": []byte("client-key-bytes")},
+	}
+	secrets = append(secrets, "live-token-bytes", base64.StdEncoding.EncodeToString([]byte("live-token-bytes")))
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(liveSecret).Build()
+	var out bytes.Buffer
+	_, err := runDiff(context.TODO(), app, cl, &out)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "Secret spark-pi-driver-kubernetes-credentials (-live +rendered)")
+	for _, secret := range secrets {
+		assert.NotContains(t, out.String(), secret)
+	}
+}
+
+func TestPruneToRendered(t *testing.T) {
+	live := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":            "driver",
+			"resourceVersion": "42",
+		},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": "spark", "terminationMessagePath": "/dev/termination-log"},
+			},
+		},
+	}
+	rendered := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "driver"},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": "spark"},
+			},
+		},
+	}
+	assert.Equal(t, rendered, pruneToRendered(live, rendered))
+}
+
+func TestRunCLISubmitThenDiff(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = v1beta2.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	app, err := readSparkApplication("-", strings.NewReader(testSparkApplicationYAML))
+	assert.NoError(t, err)
+	app.UID = "spark-pi-uid"
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(app).Build()
+
+	defaultNewKubeClient := newKubeClient
+	newKubeClient = func(string, string) (ctrlClient.Client, error) { return cl, nil }
+	defer func() { newKubeClient = defaultNewKubeClient }()
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, exitCodeOK, runCLI([]string{"submit"}, strings.NewReader(testSparkApplicationYAML), &stdout, &stderr), stderr.String())
+	stdout.Reset()
+	// The submit time and the default local dir are generated on every render, and must not show up as differences
+	assert.Equal(t, exitCodeOK, runCLI([]string{"diff"}, strings.NewReader(testSparkApplicationYAML), &stdout, &stderr), stdout.String())
+	assert.Empty(t, stdout.String())
+}
+
+func TestRunCLISubmitHelp(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, exitCodeOK, runCLI([]string{"submit", "-h"}, strings.NewReader(""), &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "must already exist in the cluster")
+	assert.Contains(t, stderr.String(), "-submission-id")
+}
+
+func TestRunSubmitRequiresTheSparkApplication(t *testing.T) {
+	app, err := readSparkApplication("-", strings.NewReader(testSparkApplicationYAML))
+	assert.NoError(t, err)
+	scheme := runtime.NewScheme()
+	_ = v1beta2.AddToScheme(scheme)
+	var out bytes.Buffer
+	err = runSubmit(context.TODO(), &NativeSubmit{}, app, "", fake.NewClientBuilder().WithScheme(scheme).Build(), &out)
+	assert.ErrorContains(t, err, "must exist in the cluster before it is submitted")
+}
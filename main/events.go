@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"nativesubmit/internal/events"
+
+	"github.com/go-logr/logr"
+	"github.com/kubeflow/spark-operator/api/v1beta2"
+	apiv1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// phaseEventReasons holds the event reason of every outcome of the resource phases
+var phaseEventReasons = map[SubmissionPhase]map[controllerutil.OperationResult]string{
+	PhaseConfigMap: {
+		controllerutil.OperationResultCreated: events.ReasonConfigMapCreated,
+		controllerutil.OperationResultUpdated: events.ReasonConfigMapUpdated,
+		controllerutil.OperationResultNone:    events.ReasonConfigMapUnchanged,
+	},
+	PhaseAdditionalResources: {
+		controllerutil.OperationResultCreated: events.ReasonAdditionalResourceCreated,
+		controllerutil.OperationResultUpdated: events.ReasonAdditionalResourceUpdated,
+		controllerutil.OperationResultNone:    events.ReasonAdditionalResourceUnchanged,
+	},
+	PhaseDriverPod: {
+		controllerutil.OperationResultCreated: events.ReasonDriverPodCreated,
+		controllerutil.OperationResultUpdated: events.ReasonDriverPodUpdated,
+		controllerutil.OperationResultNone:    events.ReasonDriverPodReused,
+	},
+	PhaseService: {
+		controllerutil.OperationResultCreated: events.ReasonServiceCreated,
+		controllerutil.OperationResultUpdated: events.ReasonServiceUpdated,
+		controllerutil.OperationResultNone:    events.ReasonServiceUnchanged,
+	},
+}
+
+// phaseFailureReasons holds the event reason of a failed resource phase
+var phaseFailureReasons = map[SubmissionPhase]string{
+	PhaseConfigMap:           events.ReasonConfigMapFailed,
+	PhaseAdditionalResources: events.ReasonAdditionalResourceFailed,
+	PhaseDriverPod:           events.ReasonDriverPodFailed,
+	PhaseService:             events.ReasonServiceFailed,
+}
+
+// operationDescriptions holds the event message prefix of every operation result
+var operationDescriptions = map[controllerutil.OperationResult]string{
+	controllerutil.OperationResultCreated: "Created",
+	controllerutil.OperationResultUpdated: "Updated",
+	controllerutil.OperationResultNone:    "Reused unchanged",
+}
+
+// eventRecorder returns the recorder supplied by the host, or one writing Events through kubeClient in the background,
+// together with the func to call once the submission no longer records events
+func (a *NativeSubmit) eventRecorder(ctx context.Context, kubeClient ctrlClient.Client) (record.EventRecorder, func()) {
+	if a.EventRecorder != nil {
+		return a.EventRecorder, func() {}
+	}
+	recorder := events.NewRecorder(ctx, kubeClient, logr.FromContextOrDiscard(ctx))
+	return recorder, recorder.Close
+}
+
+// reportPhase logs and emits the event reporting what a resource phase did to its resource
+func reportPhase(ctx context.Context, recorder record.EventRecorder, app *v1beta2.SparkApplication, phase SubmissionPhase, obj ctrlClient.Object, operationResult controllerutil.OperationResult, err error) {
+	logger := logr.FromContextOrDiscard(ctx)
+	resource := describeResources([]ctrlClient.Object{obj})
+	if err != nil {
+		logger.Error(err, "Failed to create or update resource", "resource", resource)
+		recorder.Eventf(app, apiv1.EventTypeWarning, phaseFailureReasons[phase], "Failed to create or update %s: %v", resource, err)
+		return
+	}
+	logger.Info("Submission phase completed", "resource", resource, "operation", operationResult)
+	recorder.Eventf(app, apiv1.EventTypeNormal, phaseEventReasons[phase][operationResult], "%s %s", operationDescriptions[operationResult], resource)
+}
+
+// reportReplacement logs and emits the event reporting that the resources of a previous submission are replaced
+func reportReplacement(ctx context.Context, recorder record.EventRecorder, app *v1beta2.SparkApplication, identity submissionIdentity) {
+	if identity.ReplacedSubmissionID == "" {
+		return
+	}
+	logr.FromContextOrDiscard(ctx).Info("Replacing the resources of the previous submission", "previousSubmissionID", identity.ReplacedSubmissionID)
+	recorder.Eventf(app, apiv1.EventTypeNormal, events.ReasonPreviousSubmissionReplaced, "Replacing the ConfigMap, driver Pod and Service of submission %s with the ones of submission %s",
+		identity.ReplacedSubmissionID, identity.SubmissionID)
+}
+
+// recordRenderEvents emits the events reporting the outcome of rendering the resources
+func recordRenderEvents(recorder record.EventRecorder, app *v1beta2.SparkApplication, rendered *RenderedResources, err error) {
+	if err != nil {
+		reason := events.ReasonSubmissionFailed
+		if apiErrors.IsInvalid(err) {
+			reason = events.ReasonValidationFailed
+		}
+		recorder.Event(app, apiv1.EventTypeWarning, reason, err.Error())
+		return
+	}
+	if templateFile, exists := app.Spec.SparkConf[SparkDriverPodTemplateFileKey]; exists {
+		recorder.Eventf(app, apiv1.EventTypeNormal, events.ReasonDriverPodTemplateLoaded, "Loaded driver pod template %s for driver pod %s",
+			templateFile, rendered.DriverPod.Name)
+	}
+	recorder.Event(app, apiv1.EventTypeNormal, events.ReasonResourcesRendered, fmt.Sprintf("Rendered ConfigMap %s, driver Pod %s and Service %s for submission %s",
+		rendered.ConfigMap.Name, rendered.DriverPod.Name, rendered.Service.Name, app.Status.SubmissionID))
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"nativesubmit/internal/events"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// drainEvents Helper func to collect the events buffered by a fake recorder
+func drainEvents(recorder *record.FakeRecorder) []string {
+	var recorded []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			recorded = append(recorded, event)
+		default:
+			return recorded
+		}
+	}
+}
+
+// eventReasons Helper func to extract the reasons of events formatted by a fake recorder as "Type Reason Message"
+func eventReasons(recorded []string) []string {
+	reasons := make([]string, 0, len(recorded))
+	for _, event := range recorded {
+		reasons = append(reasons, strings.Fields(event)[1])
+	}
+	return reasons
+}
+
+func TestRunAltSparkSubmitEvents(t *testing.T) {
+	t.Run("successful submission", func(t *testing.T) {
+		recorder := record.NewFakeRecorder(20)
+		cl := fake.NewClientBuilder().Build()
+		_, err := (&NativeSubmit{EventRecorder: recorder}).runAltSparkSubmit(context.TODO(), newTestSparkApplication("test-app"), "test-submission-id", cl)
+		assert.NoError(t, err)
+
+		recorded := drainEvents(recorder)
+		// The Service is created concurrently with the ConfigMap and the Driver Pod
+		assert.ElementsMatch(t, []string{
+			events.ReasonResourcesRendered,
+			events.ReasonConfigMapCreated,
+			events.ReasonDriverPodCreated,
+			events.ReasonServiceCreated,
+		}, eventReasons(recorded))
+		assert.Equal(t, events.ReasonResourcesRendered, eventReasons(recorded)[0])
+		assert.Contains(t, recorded, "Normal DriverPodCreated Created Pod default/test-app-driver")
+	})
+
+	t.Run("validation failure", func(t *testing.T) {
+		recorder := record.NewFakeRecorder(20)
+		app := newTestSparkApplication("test-app")
+		app.Spec.Image = nil
+		_, err := (&NativeSubmit{EventRecorder: recorder}).runAltSparkSubmit(context.TODO(), app, "test-submission-id", fake.NewClientBuilder().Build())
+		assert.Error(t, err)
+
+		recorded := drainEvents(recorder)
+		assert.Equal(t, []string{events.ReasonValidationFailed}, eventReasons(recorded))
+		assert.Contains(t, recorded[0], "spec.image")
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		recorder := record.NewFakeRecorder(20)
+		cl := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				if _, isService := obj.(*corev1.Service); isService {
+					return errors.New("service quota exceeded")
+				}
+				return c.Create(ctx, obj, opts...)
+			},
+		}).Build()
+		_, err := (&NativeSubmit{EventRecorder: recorder}).runAltSparkSubmit(context.TODO(), newTestSparkApplication("test-app"), "test-submission-id", cl)
+		assert.Error(t, err)
+
+		recorded := drainEvents(recorder)
+		assert.ElementsMatch(t, []string{
+			events.ReasonResourcesRendered,
+			events.ReasonConfigMapCreated,
+			events.ReasonDriverPodCreated,
+			events.ReasonServiceFailed,
+			events.ReasonRolledBack,
+		}, eventReasons(recorded))
+		assert.Contains(t, recorded[4], "Rolled back Pod default/test-app-driver, ConfigMap default/test-app-driver-conf-map")
+	})
+}
+
+func TestRunAltSparkSubmitCreatesEventsWithoutRecorder(t *testing.T) {
+	cl := fake.NewClientBuilder().Build()
+	_, err := (&NativeSubmit{}).runAltSparkSubmit(context.TODO(), newTestSparkApplication("test-app"), "test-submission-id", cl)
+	assert.NoError(t, err)
+
+	// Events are written in the background
+	assert.Eventually(t, func() bool {
+		eventList := &corev1.EventList{}
+		return cl.List(context.TODO(), eventList) == nil && len(eventList.Items) == 4
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"nativesubmit/features"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// profilerStep is a custom feature step mounting a profiler ConfigMap it contributes into the driver container
+type profilerStep struct {
+	features.NoopStep
+	err error
+}
+
+func (profilerStep) Name() string {
+	return "profiler"
+}
+
+func (s profilerStep) ConfigurePod(ctx context.Context, conf *features.DriverConf, pod *features.SparkPod) error {
+	if s.err != nil {
+		return s.err
+	}
+	pod.Pod.Labels["profiler"] = "enabled"
+	pod.Pod.Spec.Volumes = append(pod.Pod.Spec.Volumes, corev1.Volume{
+		Name:         "profiler",
+		VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: conf.App.Name + "-profiler"}}},
+	})
+	pod.Container.VolumeMounts = append(pod.Container.VolumeMounts, corev1.VolumeMount{Name: "profiler", MountPath: "/opt/profiler"})
+	return nil
+}
+
+func (profilerStep) SparkProperties(ctx context.Context, conf *features.DriverConf) (map[string]string, error) {
+	return map[string]string{"spark.driver.extraJavaOptions": "-agentpath:/opt/profiler/agent.so"}, nil
+}
+
+func (profilerStep) AdditionalResources(ctx context.Context, conf *features.DriverConf) ([]client.Object, error) {
+	return []client.Object{&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: conf.App.Name + "-profiler"},
+		Data:       map[string]string{"agent.conf": "interval=10ms"},
+	}}, nil
+}
+
+func TestRunAltSparkSubmitFeatureSteps(t *testing.T) {
+	registry := &features.Registry{}
+	assert.NoError(t, registry.Register(profilerStep{}))
+	nativeSubmit := &NativeSubmit{FeatureSteps: registry}
+
+	app := newTestSparkApplication("test-app")
+	app.Spec.SparkConf = map[string]string{"spark.driver.extraJavaOptions": "-Xss4m"}
+	cl := fake.NewClientBuilder().Build()
+	result, err := nativeSubmit.runAltSparkSubmit(context.TODO(), app, "test-submission-id", cl)
+	assert.NoError(t, err)
+	assert.Equal(t, controllerutil.OperationResultCreated, result.Operations[PhaseAdditionalResources])
+
+	driverPod := &corev1.Pod{}
+	assert.NoError(t, cl.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: result.DriverPodName}, driverPod))
+	assert.Equal(t, "enabled", driverPod.Labels["profiler"])
+	assert.Equal(t, "profiler", driverPod.Spec.Volumes[len(driverPod.Spec.Volumes)-1].Name)
+	assert.Contains(t, driverPod.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{Name: "profiler", MountPath: "/opt/profiler"})
+
+	// The contributed resource is created in the namespace of the app and owned by it
+	profilerConfigMap := &corev1.ConfigMap{}
+	assert.NoError(t, cl.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: "test-app-profiler"}, profilerConfigMap))
+	assert.Equal(t, "test-app", profilerConfigMap.OwnerReferences[0].Name)
+
+	// The contributed property is appended after, and so overrides, the one of sparkConf
+	configMap := &corev1.ConfigMap{}
+	assert.NoError(t, cl.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: result.ConfigMapName}, configMap))
+	properties := configMap.Data["spark.properties"]
+	assert.Contains(t, properties, "spark.driver.extraJavaOptions=-Xss4m\n")
+	assert.Regexp(t, "spark.driver.extraJavaOptions=-agentpath:/opt/profiler/agent.so\n$", properties)
+}
+
+func TestRenderSparkApplicationFeatureSteps(t *testing.T) {
+	registry := &features.Registry{}
+	assert.NoError(t, registry.Register(profilerStep{}))
+
+	rendered, err := (&NativeSubmit{FeatureSteps: registry}).RenderSparkApplication(newTestSparkApplication("test-app"))
+	assert.NoError(t, err)
+	objects := rendered.Objects()
+	assert.Len(t, objects, 4)
+	assert.Equal(t, rendered.ConfigMap, objects[0])
+	assert.Equal(t, "test-app-profiler", objects[1].GetName())
+	assert.Equal(t, "default", objects[1].GetNamespace())
+	assert.Equal(t, rendered.DriverPod, objects[2])
+	assert.Equal(t, rendered.Service, objects[3])
+
+	failing := &features.Registry{}
+	assert.NoError(t, failing.Register(profilerStep{err: fmt.Errorf("agent missing")}))
+	_, err = (&NativeSubmit{FeatureSteps: failing}).RenderSparkApplication(newTestSparkApplication("test-app"))
+	assert.ErrorContains(t, err, "feature step profiler failed to configure the driver pod: agent missing")
+}
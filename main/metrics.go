@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"nativesubmit/internal/metrics"
+	"time"
+
+	"github.com/kubeflow/spark-operator/api/v1beta2"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// recordSubmissionMetrics observes the end-to-end duration of a submission that started at start and counts it as a
+// success, or as a failure in phase when err is set
+func recordSubmissionMetrics(app *v1beta2.SparkApplication, phase SubmissionPhase, start time.Time, err error) {
+	duration := time.Since(start).Seconds()
+	if err == nil {
+		metrics.SubmissionDuration.WithLabelValues(metrics.ResultSuccess).Observe(duration)
+		metrics.SubmissionsTotal.WithLabelValues(app.Namespace).Inc()
+		return
+	}
+	metrics.SubmissionDuration.WithLabelValues(metrics.ResultFailure).Observe(duration)
+	metrics.SubmissionFailuresTotal.WithLabelValues(app.Namespace, failureReason(phase, err)).Inc()
+}
+
+// failureReason Helper func to classify a submission error into a low cardinality metric label. Errors without a
+// more specific cause are attributed to the phase that failed.
+func failureReason(phase SubmissionPhase, err error) string {
+	switch {
+	case apiErrors.IsInvalid(err):
+		return metrics.ReasonValidation
+	case apiErrors.IsConflict(err):
+		return metrics.ReasonConflict
+	case errors.Is(err, context.DeadlineExceeded):
+		return metrics.ReasonTimeout
+	case errors.Is(err, context.Canceled):
+		return metrics.ReasonCanceled
+	default:
+		return string(phase)
+	}
+}
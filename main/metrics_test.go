@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"nativesubmit/internal/metrics"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+func TestRunAltSparkSubmitMetrics(t *testing.T) {
+	const namespace = "metrics-test"
+	nativeSubmit := &NativeSubmit{EventRecorder: record.NewFakeRecorder(100)}
+
+	successes := testutil.ToFloat64(metrics.SubmissionsTotal.WithLabelValues(namespace))
+	app := newTestSparkApplication("test-app")
+	app.Namespace = namespace
+	_, err := nativeSubmit.runAltSparkSubmit(context.TODO(), app, "test-submission-id", fake.NewClientBuilder().Build())
+	assert.NoError(t, err)
+	assert.Equal(t, successes+1, testutil.ToFloat64(metrics.SubmissionsTotal.WithLabelValues(namespace)))
+	// render, configmap, driver-pod and service, plus additional-resources once a test submitted with feature steps
+	assert.GreaterOrEqual(t, testutil.CollectAndCount(metrics.PhaseDuration), 4)
+
+	serviceFailures := testutil.ToFloat64(metrics.SubmissionFailuresTotal.WithLabelValues(namespace, string(PhaseService)))
+	failingClient := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			if _, isService := obj.(*corev1.Service); isService {
+				return errors.New("service quota exceeded")
+			}
+			return c.Create(ctx, obj, opts...)
+		},
+	}).Build()
+	app = newTestSparkApplication("failing-app")
+	app.Namespace = namespace
+	_, err = nativeSubmit.runAltSparkSubmit(context.TODO(), app, "test-submission-id", failingClient)
+	assert.Error(t, err)
+	assert.Equal(t, serviceFailures+1, testutil.ToFloat64(metrics.SubmissionFailuresTotal.WithLabelValues(namespace, string(PhaseService))))
+
+	validationFailures := testutil.ToFloat64(metrics.SubmissionFailuresTotal.WithLabelValues(namespace, metrics.ReasonValidation))
+	app = newTestSparkApplication("invalid-app")
+	app.Namespace = namespace
+	app.Spec.Image = nil
+	_, err = nativeSubmit.runAltSparkSubmit(context.TODO(), app, "test-submission-id", fake.NewClientBuilder().Build())
+	assert.Error(t, err)
+	assert.Equal(t, validationFailures+1, testutil.ToFloat64(metrics.SubmissionFailuresTotal.WithLabelValues(namespace, metrics.ReasonValidation)))
+}
+
+func TestMetricsAreRegistered(t *testing.T) {
+	// Vectors are only gathered once they hold a series
+	metrics.SubmissionDuration.WithLabelValues(metrics.ResultSuccess)
+	metrics.PhaseDuration.WithLabelValues(string(PhaseRender))
+	metricFamilies, err := ctrlmetrics.Registry.Gather()
+	assert.NoError(t, err)
+	names := make(map[string]bool)
+	for _, metricFamily := range metricFamilies {
+		names[metricFamily.GetName()] = true
+	}
+	assert.True(t, names["native_submit_submission_duration_seconds"])
+	assert.True(t, names["native_submit_phase_duration_seconds"])
+	assert.True(t, names["native_submit_service_creation_retries_total"])
+}
+
+func TestFailureReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "validation",
+			err:  apiErrors.NewInvalid(schema.GroupKind{Kind: "SparkApplication"}, "test-app", nil),
+			want: metrics.ReasonValidation,
+		},
+		{
+			name: "wrapped conflict",
+			err:  fmt.Errorf("error while creating driver pod: %w", apiErrors.NewConflict(corev1.Resource("pods"), "test-app-driver", errors.New("owned by another app"))),
+			want: metrics.ReasonConflict,
+		},
+		{
+			name: "timeout",
+			err:  fmt.Errorf("submission aborted: %w", context.DeadlineExceeded),
+			want: metrics.ReasonTimeout,
+		},
+		{
+			name: "canceled",
+			err:  fmt.Errorf("submission aborted: %w", context.Canceled),
+			want: metrics.ReasonCanceled,
+		},
+		{
+			name: "phase failure",
+			err:  errors.New("connection refused"),
+			want: string(PhaseDriverPod),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, failureReason(PhaseDriverPod, tt.err))
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kubeflow/spark-operator/api/v1beta2"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/record"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// phaseRunner runs the resource phases of a submission. Independent phases run concurrently, so the runner
+// serialises the bookkeeping of the result and the transaction, and collects the errors of every failed phase.
+type phaseRunner struct {
+	ctx         context.Context
+	recorder    record.EventRecorder
+	app         *v1beta2.SparkApplication
+	transaction *submissionTransaction
+	result      *SubmissionResult
+
+	mu sync.Mutex
+	// failedPhase is the phase that failed first, errs holds the errors of all failed phases
+	failedPhase SubmissionPhase
+	errs        []error
+}
+
+// applyFunc creates or updates the resource of a phase
+type applyFunc func(ctx context.Context) (controllerutil.OperationResult, error)
+
+func newPhaseRunner(ctx context.Context, recorder record.EventRecorder, app *v1beta2.SparkApplication, transaction *submissionTransaction, result *SubmissionResult) *phaseRunner {
+	return &phaseRunner{ctx: ctx, recorder: recorder, app: app, transaction: transaction, result: result}
+}
+
+// run runs phase, which writes obj with apply, and reports whether it succeeded. A phase is not started once the
+// submission was cancelled or timed out.
+func (r *phaseRunner) run(phase SubmissionPhase, obj ctrlClient.Object, apply applyFunc) bool {
+	if err := r.ctx.Err(); err != nil {
+		r.fail(phase, fmt.Errorf("submission of spark application %s in namespace %s aborted: %w", r.app.Name, r.app.Namespace, err))
+		return false
+	}
+	ctx := withLogValues(r.ctx, "phase", phase)
+	start := time.Now()
+	operationResult, err := apply(ctx)
+	reportPhase(ctx, r.recorder, r.app, phase, obj, operationResult, err)
+
+	r.mu.Lock()
+	r.transaction.track(obj, operationResult)
+	r.result.recordPhase(phase, start)
+	if err == nil {
+		r.result.Operations[phase] = operationResult
+	}
+	r.mu.Unlock()
+	if err != nil {
+		r.fail(phase, fmt.Errorf("error while creating %s: %w", describeResources([]ctrlClient.Object{obj}), err))
+		return false
+	}
+	return true
+}
+
+// concurrently runs each of the sequences of phases in its own goroutine and waits for all of them to finish.
+// A sequence stops at its first failed phase, without affecting the other sequences.
+func (r *phaseRunner) concurrently(sequences ...func() bool) {
+	var wg sync.WaitGroup
+	for _, sequence := range sequences {
+		wg.Add(1)
+		go func(sequence func() bool) {
+			defer wg.Done()
+			sequence()
+		}(sequence)
+	}
+	wg.Wait()
+}
+
+// fail records the error of a failed phase
+func (r *phaseRunner) fail(phase SubmissionPhase, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.errs) == 0 {
+		r.failedPhase = phase
+	}
+	r.errs = append(r.errs, err)
+}
+
+// err returns the errors of the failed phases aggregated into one, or nil when every phase succeeded
+func (r *phaseRunner) err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return utilerrors.Reduce(utilerrors.NewAggregate(r.errs))
+}
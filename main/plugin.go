@@ -1,26 +1,108 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"nativesubmit/common"
+	"nativesubmit/features"
+	"nativesubmit/internal/tracing"
+	"os"
+	"time"
 
+	"github.com/go-logr/logr"
 	"github.com/kubeflow/spark-operator/api/v1beta2"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-type NativeSubmit struct{}
+type NativeSubmit struct {
+	// KeepResourcesOnFailure leaves the resources created by a failed submission in place instead of rolling
+	// them back. Meant for debugging only, as a retried submission then runs into the leftovers.
+	KeepResourcesOnFailure bool
+	// SubmissionTimeout bounds the whole submission, including every API server call. Zero means no deadline
+	// other than the one carried by the caller's context.
+	SubmissionTimeout time.Duration
+	// DriverPodDeletionGracePeriodSeconds is the grace period given to the driver pod of a previous submission
+	// when it is replaced on resubmission. Nil uses the termination grace period of that pod.
+	DriverPodDeletionGracePeriodSeconds *int64
+	// AllowOwnershipTakeover lets a submission adopt and overwrite existing resources owned by a different
+	// SparkApplication, e.g. one sharing the driver pod name. By default such resources fail the submission.
+	AllowOwnershipTakeover bool
+	// ApplyMode selects how resources are written: common.ApplyModeUpdate (the default) overwrites existing
+	// resources, common.ApplyModeServerSide uses server-side apply with the native-submit field manager so
+	// fields set by other controllers are preserved.
+	ApplyMode common.ApplyMode
+	// EventRecorder receives the Kubernetes events emitted on the SparkApplication for every submission step.
+	// When nil, events are created in the background through the client passed to the launch call and dropped when
+	// they cannot be written fast enough.
+	EventRecorder record.EventRecorder
+	// Logger receives the log lines of every submission, with the app, namespace, submissionID and phase as
+	// key/values. When unset, the logger carried by the context of the launch call is used, if any.
+	Logger logr.Logger
+	// FeatureSteps holds the custom feature steps run after the built-in ones when building the driver pod. When
+	// nil, the steps registered on features.DefaultRegistry are run.
+	FeatureSteps *features.Registry
+}
+
+// featureSteps Helper func to get the custom feature steps of the driver pod
+func (a *NativeSubmit) featureSteps() []features.Step {
+	if a.FeatureSteps != nil {
+		return a.FeatureSteps.Steps()
+	}
+	return features.DefaultRegistry.Steps()
+}
+
+// logger Helper func to get the logger supplied by the host, falling back to the one carried by ctx
+func (a *NativeSubmit) logger(ctx context.Context) logr.Logger {
+	if a.Logger.GetSink() != nil {
+		return a.Logger
+	}
+	return logr.FromContextOrDiscard(ctx)
+}
+
+// withLogValues Helper func to add key/values to the logger carried by ctx
+func withLogValues(ctx context.Context, keysAndValues ...interface{}) context.Context {
+	return logr.NewContext(ctx, logr.FromContextOrDiscard(ctx).WithValues(keysAndValues...))
+}
+
+// submitOptions Helper func to collect the settings passed down to the resource packages
+func (a *NativeSubmit) submitOptions() common.SubmitOptions {
+	return common.SubmitOptions{
+		DriverPodDeletionGracePeriodSeconds: a.DriverPodDeletionGracePeriodSeconds,
+		AllowOwnershipTakeover:              a.AllowOwnershipTakeover,
+		ApplyMode:                           a.ApplyMode,
+	}
+}
 
 func (a *NativeSubmit) LaunchSparkApplication(app *v1beta2.SparkApplication, cl client.Client) error {
+	return a.LaunchSparkApplicationWithContext(context.Background(), app, cl)
+}
+
+// LaunchSparkApplicationWithContext is LaunchSparkApplication with a caller supplied context. The submission is
+// aborted promptly once ctx is cancelled or the SubmissionTimeout expires, and the resources created so far are rolled back.
+func (a *NativeSubmit) LaunchSparkApplicationWithContext(ctx context.Context, app *v1beta2.SparkApplication, cl client.Client) error {
+	_, err := a.LaunchSparkApplicationWithResult(ctx, app, cl)
+	return err
+}
+
+// LaunchSparkApplicationWithResult launches the Spark Application and reports what was created. On success the
+// DriverInfo of the application status is populated as well.
+func (a *NativeSubmit) LaunchSparkApplicationWithResult(ctx context.Context, app *v1beta2.SparkApplication, cl client.Client) (*SubmissionResult, error) {
 	if app == nil {
-		return fmt.Errorf("spark application cannot be nil")
+		return nil, fmt.Errorf("spark application cannot be nil")
 	}
-	fmt.Println("Launching spark application")
-	return runAltSparkSubmitWrapper(app, cl)
+	// Continue the trace of the request that created the Spark Application, if it handed one over
+	ctx, span := tracing.StartSpan(tracing.ExtractTraceContext(ctx, app), "LaunchSparkApplication")
+	result, err := a.runAltSparkSubmitWrapper(tracing.WithApplication(ctx, app), app, cl)
+	tracing.EndSpan(span, err)
+	return result, err
 }
 
 func New() interface{} {
 	return &NativeSubmit{}
 }
 
+// main runs the native-submit command line tool; it is not invoked when the package is loaded as an operator plugin
 func main() {
-	fmt.Println("Native Submit Plugin")
+	os.Exit(runCLI(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
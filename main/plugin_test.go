@@ -1,11 +1,17 @@
 package main
 
 import (
+	"context"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/go-logr/logr/funcr"
 	"github.com/kubeflow/spark-operator/api/v1beta2"
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
@@ -77,9 +83,51 @@ func TestNativeSubmit_LaunchSparkApplication(t *testing.T) {
 	}
 }
 
+func TestNativeSubmit_LaunchSparkApplicationWithContextCancelled(t *testing.T) {
+	app := newTestSparkApplication("test-app")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cl := fake.NewClientBuilder().Build()
+	err := (&NativeSubmit{}).LaunchSparkApplicationWithContext(ctx, app, cl)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	pods := &corev1.PodList{}
+	assert.NoError(t, cl.List(context.Background(), pods))
+	assert.Empty(t, pods.Items)
+}
+
 func TestNew(t *testing.T) {
 	result := New()
 	assert.NotNil(t, result)
 	_, ok := result.(*NativeSubmit)
 	assert.True(t, ok)
 }
+
+func TestNativeSubmit_LaunchSparkApplicationLogs(t *testing.T) {
+	// Phases log concurrently
+	var mu sync.Mutex
+	var lines []string
+	logger := funcr.New(func(prefix, args string) {
+		mu.Lock()
+		defer mu.Unlock()
+		lines = append(lines, args)
+	}, funcr.Options{})
+
+	app := newTestSparkApplication("test-app")
+	app.Status.SubmissionID = "test-submission-id"
+	nativeSubmit := &NativeSubmit{EventRecorder: record.NewFakeRecorder(100), Logger: logger}
+	_, err := nativeSubmit.LaunchSparkApplicationWithResult(context.TODO(), app, fake.NewClientBuilder().Build())
+	assert.NoError(t, err)
+
+	if assert.Len(t, lines, 5) {
+		assert.Contains(t, lines[0], `"msg"="Launching spark application" "app"="test-app" "namespace"="default"`)
+		var phases []string
+		for _, line := range lines[1:4] {
+			assert.Contains(t, line, `"submissionID"="test-submission-id"`)
+			phases = append(phases, line[strings.Index(line, `"phase"=`):strings.Index(line, ` "resource"`)])
+		}
+		assert.ElementsMatch(t, []string{`"phase"="configmap"`, `"phase"="driver-pod"`, `"phase"="service"`}, phases)
+		assert.Contains(t, lines[4], `"msg"="Submitted spark application"`)
+	}
+}
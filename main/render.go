@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"nativesubmit/common"
+	"nativesubmit/features"
+	"nativesubmit/internal/configmap"
+	"nativesubmit/internal/driver"
+	"nativesubmit/internal/service"
+	"nativesubmit/internal/validation"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/kubeflow/spark-operator/api/v1beta2"
+	apiv1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RenderedResources holds the objects native submit creates for a Spark Application
+type RenderedResources struct {
+	ConfigMap *apiv1.ConfigMap
+	DriverPod *apiv1.Pod
+	Service   *apiv1.Service
+	// AdditionalResources holds the objects contributed by the feature steps of the driver pod
+	AdditionalResources []ctrlClient.Object
+	// SparkProperties is the spark.properties content stored in the ConfigMap and read by the driver
+	SparkProperties string
+}
+
+// Objects returns the rendered objects in the order they are created
+func (r *RenderedResources) Objects() []ctrlClient.Object {
+	objects := []ctrlClient.Object{r.ConfigMap}
+	objects = append(objects, r.AdditionalResources...)
+	return append(objects, r.DriverPod, r.Service)
+}
+
+// RenderSparkApplication builds the ConfigMap, Driver Pod and Service for the Spark Application without
+// touching the API server. The supplied Spark Application is left unmodified. The contents of the Secrets contributed
+// by the built-in feature steps are redacted, as they are only built when the application is submitted.
+func (a *NativeSubmit) RenderSparkApplication(app *v1beta2.SparkApplication) (*RenderedResources, error) {
+	if app == nil {
+		return nil, fmt.Errorf("spark application cannot be nil")
+	}
+	return renderResources(context.Background(), app.DeepCopy(), submissionIdentity{SubmissionID: app.Status.SubmissionID}, a.featureSteps(), true)
+}
+
+// ValidateSparkApplication reports every problem with the Spark Application that would make its submission fail,
+// with the path of the offending field. An empty list means the application can be submitted.
+func (a *NativeSubmit) ValidateSparkApplication(app *v1beta2.SparkApplication) field.ErrorList {
+	return validation.Validate(app)
+}
+
+// renderResources builds the resources in the same order runAltSparkSubmit creates them.
+// Like the submission itself, it records the generated Spark Application ID and Submission ID on the app status.
+// The Spark Application ID and Service name of the identity are generated when empty. featureSteps run after the
+// built-in steps of the driver pod, their Spark properties are appended to the ConfigMap. A dry run renders the
+// resources to be shown or compared, without the secrets of the submission host.
+func renderResources(ctx context.Context, app *v1beta2.SparkApplication, identity submissionIdentity, featureSteps []features.Step, dryRun bool) (*RenderedResources, error) {
+	// Reject invalid input before anything is built, the builders assume well formed values
+	if errs := validation.Validate(app); len(errs) > 0 {
+		return nil, apiErrors.NewInvalid(v1beta2.SchemeGroupVersion.WithKind("SparkApplication").GroupKind(), app.Name, errs)
+	}
+
+	// Captured before the ConfigMap is built, as building it filters the local dir volumes out of the app spec
+	appSpecVolumeMounts := app.Spec.Driver.VolumeMounts
+	appSpecVolumes := app.Spec.Volumes
+
+	submissionID := identity.SubmissionID
+	createdApplicationId := identity.SparkApplicationID
+	if createdApplicationId == "" {
+		// Create Application ID with the convention followed in Scala/Java
+		uuidString := strings.ReplaceAll(uuid.New().String(), "-", "")
+		createdApplicationId = fmt.Sprintf("%s-%s", Spark, uuidString)
+	}
+
+	//Update Application CRD Instance with Spark Application ID
+	app.Status.SparkApplicationID = createdApplicationId
+
+	//Create Spark Application ConfigMap Name with the convention followed in Scala/Java
+	driverConfigMapName := fmt.Sprintf("%s%s", common.GetDriverPodName(app), ConfigMapExtension)
+	serviceName := identity.ServiceName
+	if serviceName == "" {
+		serviceName = getServiceName(app)
+	}
+
+	//Update Application CRD Instance with Submission ID
+	app.Status.SubmissionID = submissionID
+
+	serviceLabels := getServiceLabels(app, submissionID, createdApplicationId)
+
+	configMap, err := configmap.Build(app, submissionID, createdApplicationId, driverConfigMapName, serviceName)
+	if err != nil {
+		return nil, fmt.Errorf("error while building configmap %s in namespace %s: %w", driverConfigMapName, app.Namespace, err)
+	}
+
+	driverPod, contributions, err := driver.BuildWithFeatureSteps(ctx, &features.DriverConf{
+		App:                 app,
+		ConfigMapName:       driverConfigMapName,
+		Labels:              serviceLabels,
+		AppSpecVolumeMounts: appSpecVolumeMounts,
+		AppSpecVolumes:      appSpecVolumes,
+		DryRun:              dryRun,
+	}, featureSteps)
+	if err != nil {
+		return nil, fmt.Errorf("error while building driver pod %s in namespace %s: %w", common.GetDriverPodName(app), app.Namespace, err)
+	}
+	configmap.AppendSparkProperties(configMap, contributions.SparkProperties)
+
+	driverService, err := service.Build(ctx, app, serviceLabels, createdApplicationId, serviceName)
+	if err != nil {
+		return nil, fmt.Errorf("error while building driver service %s in namespace %s: %w", serviceName, app.Namespace, err)
+	}
+
+	return &RenderedResources{
+		ConfigMap:           configMap,
+		DriverPod:           driverPod,
+		Service:             driverService,
+		AdditionalResources: contributions.AdditionalResources,
+		SparkProperties:     configMap.Data[configmap.SparkPropertiesFileName],
+	}, nil
+}
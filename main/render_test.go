@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"nativesubmit/common"
+	"nativesubmit/internal/executor"
+	"testing"
+
+	"github.com/kubeflow/spark-operator/api/v1beta2"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestNativeSubmit_RenderSparkApplication(t *testing.T) {
+	image := "gcr.io/spark-operator/spark:v3.1.1"
+	mainClass := "org.apache.spark.examples.SparkPi"
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-app",
+			Namespace: "default",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Type:      v1beta2.SparkApplicationTypeScala,
+			Mode:      v1beta2.DeployModeCluster,
+			Image:     &image,
+			MainClass: &mainClass,
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			SubmissionID: "test-submission-id",
+		},
+	}
+
+	ns := &NativeSubmit{}
+	rendered, err := ns.RenderSparkApplication(app)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "test-app-driver-conf-map", rendered.ConfigMap.Name)
+	assert.Equal(t, "ConfigMap", rendered.ConfigMap.Kind)
+	assert.Equal(t, "test-app-driver", rendered.DriverPod.Name)
+	assert.Equal(t, "Pod", rendered.DriverPod.Kind)
+	assert.Equal(t, "test-app-driver-svc", rendered.Service.Name)
+	assert.Equal(t, "Service", rendered.Service.Kind)
+
+	appID := rendered.DriverPod.Labels[SparkApplicationSelectorLabel]
+	assert.Contains(t, appID, "spark-")
+	assert.Equal(t, "test-submission-id", rendered.DriverPod.Labels[SparkAppSubmissionIDAnnotation])
+	assert.Equal(t, rendered.DriverPod.Labels, rendered.Service.Spec.Selector)
+	assert.Contains(t, rendered.SparkProperties, "spark.app.id="+appID)
+	assert.Contains(t, rendered.SparkProperties, "spark.driver.host=test-app-driver-svc.default.svc")
+	assert.Equal(t, rendered.ConfigMap.Data["spark.properties"], rendered.SparkProperties)
+
+	// Rendering must not record anything on the supplied application
+	assert.Empty(t, app.Status.SparkApplicationID)
+
+	_, err = ns.RenderSparkApplication(nil)
+	assert.Error(t, err)
+}
+
+func TestRunAltSparkSubmitRejectsInvalidApplication(t *testing.T) {
+	app := newTestSparkApplication("test-app")
+	app.Spec.Driver.Memory = common.StringPointer("lots")
+	app.Spec.SparkConf = map[string]string{"spark.driver.port": "http"}
+	cl := fake.NewClientBuilder().Build()
+
+	result, err := (&NativeSubmit{}).runAltSparkSubmit(context.TODO(), app, "test-submission-id", cl)
+	assert.Nil(t, result)
+	assert.True(t, apiErrors.IsInvalid(err), "expected an invalid error, got %v", err)
+	assert.ErrorContains(t, err, "spec.driver.memory")
+	assert.ErrorContains(t, err, "spec.sparkConf[spark.driver.port]")
+
+	configMaps := &corev1.ConfigMapList{}
+	assert.NoError(t, cl.List(context.TODO(), configMaps))
+	assert.Empty(t, configMaps.Items)
+	assert.Len(t, (&NativeSubmit{}).ValidateSparkApplication(app), 2)
+}
+
+func TestRenderSparkApplicationExecutorPodTemplate(t *testing.T) {
+	app := newTestSparkApplication("test-app")
+	app.Spec.Executor.Tolerations = []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}}
+
+	rendered, err := (&NativeSubmit{}).RenderSparkApplication(app)
+	assert.NoError(t, err)
+	assert.Contains(t, rendered.ConfigMap.Data[executor.PodTemplateFileName], "key: dedicated")
+	assert.Contains(t, rendered.SparkProperties, "spark.kubernetes.executor.podTemplateFile="+executor.PodTemplateFilePath+"\n")
+	assert.Contains(t, rendered.SparkProperties, "spark.kubernetes.executor.podTemplateContainerName="+executor.PodTemplateContainerName+"\n")
+
+	// The template is mounted next to spark.properties
+	sparkConfVolume := rendered.DriverPod.Spec.Volumes[0]
+	assert.Contains(t, sparkConfVolume.ConfigMap.Items, corev1.KeyToPath{Key: executor.PodTemplateFileName, Path: executor.PodTemplateFileName, Mode: common.Int32Pointer(420)})
+
+	// Without template fields in the executor spec nothing is generated
+	rendered, err = (&NativeSubmit{}).RenderSparkApplication(newTestSparkApplication("test-app"))
+	assert.NoError(t, err)
+	assert.NotContains(t, rendered.ConfigMap.Data, executor.PodTemplateFileName)
+	assert.NotContains(t, rendered.SparkProperties, "spark.kubernetes.executor.podTemplateFile")
+	assert.Len(t, rendered.DriverPod.Spec.Volumes[0].ConfigMap.Items, 2)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"nativesubmit/common"
+
+	"github.com/kubeflow/spark-operator/api/v1beta2"
+	apiv1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// submissionIdentity holds the generated identifiers of a submission, so a retried submission renders the same objects
+type submissionIdentity struct {
+	SubmissionID       string
+	SparkApplicationID string
+	ServiceName        string
+	// ReplacedSubmissionID is the earlier submission whose resources this one replaces, set only when the restart
+	// policy lets the application be resubmitted
+	ReplacedSubmissionID string
+}
+
+// resolveSubmissionIdentity looks up the ConfigMap, Driver Pod and Service left behind by an earlier attempt of the
+// same submission. When they belong to this submission their Spark Application ID and Service name are reused.
+// Resources of another submission are replaced when the restart policy lets the application be resubmitted, which is
+// recorded in ReplacedSubmissionID; otherwise a conflict error is returned instead of overwriting them. Resources
+// owned by a different Spark Application are a conflict as well, unless allowTakeover is set in which case they are
+// ignored here and adopted later.
+func resolveSubmissionIdentity(ctx context.Context, app *v1beta2.SparkApplication, submissionID string, kubeClient ctrlClient.Client, allowTakeover bool) (submissionIdentity, error) {
+	identity := submissionIdentity{SubmissionID: submissionID}
+	if submissionID == "" {
+		return identity, nil
+	}
+
+	driverConfigMapName := fmt.Sprintf("%s%s", common.GetDriverPodName(app), ConfigMapExtension)
+	candidates := []struct {
+		resource string
+		name     string
+		obj      ctrlClient.Object
+	}{
+		{resource: "configmaps", name: driverConfigMapName, obj: &apiv1.ConfigMap{}},
+		{resource: "pods", name: common.GetDriverPodName(app), obj: &apiv1.Pod{}},
+		// A generated Service name is random and cannot collide, only the name derived from the Driver Pod is checked
+		{resource: "services", name: getServiceName(app), obj: &apiv1.Service{}},
+	}
+	for _, candidate := range candidates {
+		key := ctrlClient.ObjectKey{Namespace: app.Namespace, Name: candidate.name}
+		if err := kubeClient.Get(ctx, key, candidate.obj); err != nil {
+			if apiErrors.IsNotFound(err) {
+				continue
+			}
+			return identity, fmt.Errorf("failed to get %s %s in namespace %s: %w", candidate.resource, key.Name, key.Namespace, err)
+		}
+		owner := &metav1.ObjectMeta{OwnerReferences: []metav1.OwnerReference{*common.GetOwnerReference(app)}}
+		if err := common.CheckOwnership(candidate.obj, owner, candidate.resource, false); err != nil {
+			if allowTakeover {
+				continue
+			}
+			return identity, err
+		}
+		existingSubmissionID, labelled := submissionOf(candidate.obj)
+		if !labelled {
+			// Not created by a submission we can identify, it is updated in place as before
+			continue
+		}
+		if existingSubmissionID != submissionID {
+			if !allowsResubmission(app) {
+				return identity, newSubmissionConflict(candidate.resource, key.Name, existingSubmissionID, submissionID)
+			}
+			// Left behind by the previous run of a restarted application, it gets replaced
+			if identity.ReplacedSubmissionID == "" {
+				identity.ReplacedSubmissionID = existingSubmissionID
+			}
+			continue
+		}
+		if identity.SparkApplicationID == "" {
+			identity.SparkApplicationID = candidate.obj.GetLabels()[SparkApplicationSelectorLabel]
+		}
+	}
+
+	if identity.SparkApplicationID == "" {
+		return identity, nil
+	}
+	// The Service name may have been generated randomly, find it through the selector label it shares with the Driver Pod
+	services := &apiv1.ServiceList{}
+	if err := kubeClient.List(ctx, services, ctrlClient.InNamespace(app.Namespace), ctrlClient.MatchingLabels{
+		SparkApplicationSelectorLabel: identity.SparkApplicationID,
+	}); err != nil {
+		return identity, fmt.Errorf("failed to list driver services in namespace %s: %w", app.Namespace, err)
+	}
+	if len(services.Items) > 0 {
+		identity.ServiceName = services.Items[0].Name
+	}
+	return identity, nil
+}
+
+// submissionOf Helper func to get the submission that created an object. The Driver Service carries it in its
+// selector, which it shares with the Driver Pod, the other resources in their labels.
+func submissionOf(obj ctrlClient.Object) (string, bool) {
+	if driverService, isService := obj.(*apiv1.Service); isService {
+		submissionID, selected := driverService.Spec.Selector[SparkAppSubmissionIDAnnotation]
+		return submissionID, selected
+	}
+	submissionID, labelled := obj.GetLabels()[SparkAppSubmissionIDAnnotation]
+	return submissionID, labelled
+}
+
+// allowsResubmission Helper func to check whether the restart policy lets the operator submit the application again
+func allowsResubmission(app *v1beta2.SparkApplication) bool {
+	switch app.Spec.RestartPolicy.Type {
+	case v1beta2.RestartPolicyOnFailure, v1beta2.RestartPolicyAlways:
+		return true
+	default:
+		return false
+	}
+}
+
+// newSubmissionConflict Helper func to report a resource owned by a different submission as an API conflict,
+// so callers can recognise it with apiErrors.IsConflict
+func newSubmissionConflict(resource string, name string, existingSubmissionID string, submissionID string) error {
+	return apiErrors.NewConflict(schema.GroupResource{Resource: resource}, name,
+		fmt.Errorf("it belongs to submission %s, not to submission %s", existingSubmissionID, submissionID))
+}
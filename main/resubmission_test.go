@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"nativesubmit/common"
+	"nativesubmit/internal/configmap"
+	"nativesubmit/internal/driver"
+	"nativesubmit/internal/service"
+	"strings"
+	"testing"
+
+	"github.com/kubeflow/spark-operator/api/v1beta2"
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func TestRunAltSparkSubmitIsIdempotentPerSubmissionID(t *testing.T) {
+	// A name long enough for the Service name to be generated randomly
+	app := newTestSparkApplication(strings.Repeat("a", 60))
+	cl := fake.NewClientBuilder().Build()
+
+	first, err := (&NativeSubmit{}).runAltSparkSubmit(context.TODO(), app.DeepCopy(), "submission-1", cl)
+	assert.NoError(t, err)
+	second, err := (&NativeSubmit{}).runAltSparkSubmit(context.TODO(), app.DeepCopy(), "submission-1", cl)
+	assert.NoError(t, err)
+
+	assert.Equal(t, first.SparkApplicationID, second.SparkApplicationID)
+	assert.Equal(t, first.ServiceName, second.ServiceName)
+
+	services := &apiv1.ServiceList{}
+	assert.NoError(t, cl.List(context.TODO(), services, ctrlClient.InNamespace("default")))
+	assert.Len(t, services.Items, 1)
+
+	configMap := &apiv1.ConfigMap{}
+	assert.NoError(t, cl.Get(context.TODO(), ctrlClient.ObjectKey{Namespace: "default", Name: first.ConfigMapName}, configMap))
+	assert.Equal(t, "submission-1", configMap.Labels[SparkAppSubmissionIDAnnotation])
+	assert.Equal(t, first.SparkApplicationID, configMap.Labels[SparkApplicationSelectorLabel])
+}
+
+func TestRunAltSparkSubmitConflictsWithOlderSubmission(t *testing.T) {
+	app := newTestSparkApplication("test-app")
+	cl := fake.NewClientBuilder().Build()
+
+	first, err := (&NativeSubmit{}).runAltSparkSubmit(context.TODO(), app.DeepCopy(), "submission-1", cl)
+	assert.NoError(t, err)
+
+	result, err := (&NativeSubmit{}).runAltSparkSubmit(context.TODO(), app.DeepCopy(), "submission-2", cl)
+	assert.Nil(t, result)
+	assert.True(t, apiErrors.IsConflict(err), "expected a conflict, got %v", err)
+	assert.Contains(t, err.Error(), "submission-1")
+
+	// The resources of the older submission are left untouched
+	configMap := &apiv1.ConfigMap{}
+	assert.NoError(t, cl.Get(context.TODO(), ctrlClient.ObjectKey{Namespace: "default", Name: first.ConfigMapName}, configMap))
+	assert.Equal(t, "submission-1", configMap.Labels[SparkAppSubmissionIDAnnotation])
+	assert.Contains(t, configMap.Data["spark.properties"], first.SparkApplicationID)
+}
+
+func TestRunAltSparkSubmitReplacesPreviousSubmissionOnRestart(t *testing.T) {
+	app := newTestSparkApplication("test-app")
+	app.Spec.RestartPolicy = v1beta2.RestartPolicy{Type: v1beta2.RestartPolicyOnFailure}
+	cl := fake.NewClientBuilder().Build()
+	gracePeriod := int64(0)
+	recorder := record.NewFakeRecorder(20)
+	nativeSubmit := &NativeSubmit{DriverPodDeletionGracePeriodSeconds: &gracePeriod, EventRecorder: recorder}
+
+	first, err := nativeSubmit.runAltSparkSubmit(context.TODO(), app.DeepCopy(), "submission-1", cl)
+	assert.NoError(t, err)
+	second, err := nativeSubmit.runAltSparkSubmit(context.TODO(), app.DeepCopy(), "submission-2", cl)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.SparkApplicationID, second.SparkApplicationID)
+	assert.Equal(t, controllerutil.OperationResultCreated, second.Operations[PhaseDriverPod])
+
+	driverPod := &apiv1.Pod{}
+	assert.NoError(t, cl.Get(context.TODO(), ctrlClient.ObjectKey{Namespace: "default", Name: second.DriverPodName}, driverPod))
+	assert.Equal(t, "submission-2", driverPod.Labels[SparkAppSubmissionIDAnnotation])
+	assert.Equal(t, second.SparkApplicationID, driverPod.Labels[SparkApplicationSelectorLabel])
+
+	// The ConfigMap and the Service are replaced as well, and the replacement is reported
+	assert.Equal(t, controllerutil.OperationResultUpdated, second.Operations[PhaseConfigMap])
+	configMap := &apiv1.ConfigMap{}
+	assert.NoError(t, cl.Get(context.TODO(), ctrlClient.ObjectKey{Namespace: "default", Name: second.ConfigMapName}, configMap))
+	assert.Equal(t, "submission-2", configMap.Labels[SparkAppSubmissionIDAnnotation])
+	assert.Contains(t, configMap.Data["spark.properties"], second.SparkApplicationID)
+	driverService := &apiv1.Service{}
+	assert.NoError(t, cl.Get(context.TODO(), ctrlClient.ObjectKey{Namespace: "default", Name: second.ServiceName}, driverService))
+	assert.Equal(t, "submission-2", driverService.Spec.Selector[SparkAppSubmissionIDAnnotation])
+	assert.Contains(t, drainEvents(recorder), "Normal PreviousSubmissionReplaced Replacing the ConfigMap, driver Pod and Service of submission submission-1 with the ones of submission submission-2")
+}
+
+func TestResolveSubmissionIdentity(t *testing.T) {
+	otherApp := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "other-app", Namespace: "default", UID: "other-app-uid"}}
+	tests := []struct {
+		name          string
+		objects       []ctrlClient.Object
+		submissionID  string
+		restartPolicy v1beta2.RestartPolicyType
+		want          submissionIdentity
+		wantConflict  bool
+	}{
+		{
+			name:         "no existing resources",
+			submissionID: "submission-1",
+			want:         submissionIdentity{SubmissionID: "submission-1"},
+		},
+		{
+			name:         "driver pod of the same submission",
+			submissionID: "submission-1",
+			objects: []ctrlClient.Object{
+				&apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test-app-driver", Namespace: "default", Labels: map[string]string{
+					SparkAppSubmissionIDAnnotation: "submission-1",
+					SparkApplicationSelectorLabel:  "spark-1234",
+				}}},
+				&apiv1.Service{ObjectMeta: metav1.ObjectMeta{Name: "spark-random-driver-svc", Namespace: "default", Labels: map[string]string{
+					SparkApplicationSelectorLabel: "spark-1234",
+				}}},
+			},
+			want: submissionIdentity{SubmissionID: "submission-1", SparkApplicationID: "spark-1234", ServiceName: "spark-random-driver-svc"},
+		},
+		{
+			name:         "driver pod of an older submission",
+			submissionID: "submission-2",
+			objects: []ctrlClient.Object{
+				&apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test-app-driver", Namespace: "default", Labels: map[string]string{
+					SparkAppSubmissionIDAnnotation: "submission-1",
+				}}},
+			},
+			wantConflict: true,
+		},
+		{
+			name:         "unlabelled configmap",
+			submissionID: "submission-1",
+			objects: []ctrlClient.Object{
+				&apiv1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test-app-driver-conf-map", Namespace: "default"}},
+			},
+			want: submissionIdentity{SubmissionID: "submission-1"},
+		},
+		{
+			name: "empty submission ID",
+			objects: []ctrlClient.Object{
+				&apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test-app-driver", Namespace: "default", Labels: map[string]string{
+					SparkAppSubmissionIDAnnotation: "submission-1",
+				}}},
+			},
+			want: submissionIdentity{},
+		},
+		{
+			name:         "driver service of an older submission",
+			submissionID: "submission-2",
+			objects: []ctrlClient.Object{
+				&apiv1.Service{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-app-driver-svc", Namespace: "default"},
+					Spec:       apiv1.ServiceSpec{Selector: map[string]string{SparkAppSubmissionIDAnnotation: "submission-1"}},
+				},
+			},
+			wantConflict: true,
+		},
+		{
+			name:         "driver service of another application",
+			submissionID: "submission-1",
+			objects: []ctrlClient.Object{
+				&apiv1.Service{ObjectMeta: metav1.ObjectMeta{Name: "test-app-driver-svc", Namespace: "default",
+					OwnerReferences: []metav1.OwnerReference{*common.GetOwnerReference(otherApp)}}},
+			},
+			wantConflict: true,
+		},
+		{
+			name:          "configmap of an older submission on restart",
+			submissionID:  "submission-2",
+			restartPolicy: v1beta2.RestartPolicyOnFailure,
+			objects: []ctrlClient.Object{
+				&apiv1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test-app-driver-conf-map", Namespace: "default", Labels: map[string]string{
+					SparkAppSubmissionIDAnnotation: "submission-1",
+					SparkApplicationSelectorLabel:  "spark-1234",
+				}}},
+			},
+			want: submissionIdentity{SubmissionID: "submission-2", ReplacedSubmissionID: "submission-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestSparkApplication("test-app")
+			app.Spec.RestartPolicy.Type = tt.restartPolicy
+			cl := fake.NewClientBuilder().WithObjects(tt.objects...).Build()
+			identity, err := resolveSubmissionIdentity(context.TODO(), app, tt.submissionID, cl, false)
+			if tt.wantConflict {
+				assert.True(t, apiErrors.IsConflict(err), "expected a conflict, got %v", err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, identity)
+		})
+	}
+}
+
+func TestRunAltSparkSubmitRefusesResourcesOfAnotherApplication(t *testing.T) {
+	app := newTestSparkApplication("test-app")
+	app.UID = "test-app-uid"
+	otherApp := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "other-app",
+			Namespace: "default",
+			UID:       "other-app-uid",
+		},
+	}
+	newClient := func() ctrlClient.Client {
+		return fake.NewClientBuilder().WithObjects(&apiv1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name:            "test-app-driver-conf-map",
+			Namespace:       "default",
+			OwnerReferences: []metav1.OwnerReference{*common.GetOwnerReference(otherApp)},
+		}}).Build()
+	}
+
+	cl := newClient()
+	result, err := (&NativeSubmit{}).runAltSparkSubmit(context.TODO(), app.DeepCopy(), "submission-1", cl)
+	assert.Nil(t, result)
+	assert.True(t, apiErrors.IsConflict(err), "expected a conflict, got %v", err)
+	assert.Contains(t, err.Error(), "other-app")
+	pods := &apiv1.PodList{}
+	assert.NoError(t, cl.List(context.TODO(), pods))
+	assert.Empty(t, pods.Items)
+
+	cl = newClient()
+	result, err = (&NativeSubmit{AllowOwnershipTakeover: true}).runAltSparkSubmit(context.TODO(), app.DeepCopy(), "submission-1", cl)
+	assert.NoError(t, err)
+	configMap := &apiv1.ConfigMap{}
+	assert.NoError(t, cl.Get(context.TODO(), ctrlClient.ObjectKey{Namespace: "default", Name: result.ConfigMapName}, configMap))
+	assert.Len(t, configMap.OwnerReferences, 1)
+	assert.Equal(t, app.UID, configMap.OwnerReferences[0].UID)
+}
+
+func TestCreateOrUpdateAdoptsEachKindOnTakeover(t *testing.T) {
+	app := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "test-app", Namespace: "default", UID: "test-app-uid"}}
+	otherApp := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "other-app", Namespace: "default", UID: "other-app-uid"}}
+	meta := func(owner *v1beta2.SparkApplication, labels map[string]string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Name:            "test-app-driver",
+			Namespace:       "default",
+			Labels:          labels,
+			OwnerReferences: []metav1.OwnerReference{*common.GetOwnerReference(owner)},
+		}
+	}
+	foreign := func(obj ctrlClient.Object) ctrlClient.Object {
+		obj.SetLabels(map[string]string{"team": "data", driver.SubmissionIDLabel: "submission-1"})
+		obj.SetFinalizers([]string{"example.com/finalizer"})
+		return obj
+	}
+	submission := map[string]string{driver.SubmissionIDLabel: "submission-1"}
+
+	tests := []struct {
+		name     string
+		existing ctrlClient.Object
+		desired  ctrlClient.Object
+		apply    func(cl ctrlClient.Client, obj ctrlClient.Object, opts common.SubmitOptions) (controllerutil.OperationResult, error)
+	}{
+		{
+			name:     "configmap",
+			existing: foreign(&apiv1.ConfigMap{ObjectMeta: meta(otherApp, nil)}),
+			desired:  &apiv1.ConfigMap{ObjectMeta: meta(app, submission), Data: map[string]string{"spark.properties": ""}},
+			apply: func(cl ctrlClient.Client, obj ctrlClient.Object, opts common.SubmitOptions) (controllerutil.OperationResult, error) {
+				return configmap.CreateOrUpdate(context.TODO(), obj.(*apiv1.ConfigMap), cl, opts)
+			},
+		},
+		{
+			name:     "driver pod",
+			existing: foreign(&apiv1.Pod{ObjectMeta: meta(otherApp, nil)}),
+			desired:  &apiv1.Pod{ObjectMeta: meta(app, submission)},
+			apply: func(cl ctrlClient.Client, obj ctrlClient.Object, opts common.SubmitOptions) (controllerutil.OperationResult, error) {
+				return driver.CreateOrUpdate(context.TODO(), obj.(*apiv1.Pod), cl, opts)
+			},
+		},
+		{
+			name:     "driver service",
+			existing: foreign(&apiv1.Service{ObjectMeta: meta(otherApp, nil)}),
+			desired:  &apiv1.Service{ObjectMeta: meta(app, submission)},
+			apply: func(cl ctrlClient.Client, obj ctrlClient.Object, opts common.SubmitOptions) (controllerutil.OperationResult, error) {
+				return service.CreateOrUpdate(context.TODO(), obj.(*apiv1.Service), cl, opts)
+			},
+		},
+		{
+			name:     "additional resource",
+			existing: foreign(&apiv1.Secret{ObjectMeta: meta(otherApp, nil)}),
+			desired:  &apiv1.Secret{ObjectMeta: meta(app, submission)},
+			apply: func(cl ctrlClient.Client, obj ctrlClient.Object, opts common.SubmitOptions) (controllerutil.OperationResult, error) {
+				return common.CreateOrUpdate(context.TODO(), cl, obj, "secrets", opts)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cl := fake.NewClientBuilder().WithObjects(tt.existing.DeepCopyObject().(ctrlClient.Object)).Build()
+			_, err := tt.apply(cl, tt.desired.DeepCopyObject().(ctrlClient.Object), common.SubmitOptions{})
+			assert.True(t, apiErrors.IsConflict(err), "expected a conflict, got %v", err)
+
+			operationResult, err := tt.apply(cl, tt.desired.DeepCopyObject().(ctrlClient.Object), common.SubmitOptions{AllowOwnershipTakeover: true})
+			assert.NoError(t, err)
+			assert.Equal(t, controllerutil.OperationResultUpdated, operationResult)
+
+			adopted := tt.existing.DeepCopyObject().(ctrlClient.Object)
+			assert.NoError(t, cl.Get(context.TODO(), ctrlClient.ObjectKeyFromObject(tt.existing), adopted))
+			assert.Equal(t, []metav1.OwnerReference{*common.GetOwnerReference(app)}, adopted.GetOwnerReferences())
+			assert.Equal(t, "data", adopted.GetLabels()["team"])
+			assert.Equal(t, []string{"example.com/finalizer"}, adopted.GetFinalizers())
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"nativesubmit/internal/metrics"
+	"nativesubmit/internal/service"
+	"time"
+
+	"github.com/kubeflow/spark-operator/api/v1beta2"
+	apiv1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// SubmissionPhase names a step of the native submission
+type SubmissionPhase string
+
+const (
+	// PhaseRender builds the ConfigMap, Driver Pod and Service from the Spark Application
+	PhaseRender SubmissionPhase = "render"
+	// PhaseConfigMap creates or updates the Spark Application ConfigMap
+	PhaseConfigMap SubmissionPhase = "configmap"
+	// PhaseAdditionalResources creates or updates the resources contributed by the feature steps of the Driver Pod
+	PhaseAdditionalResources SubmissionPhase = "additional-resources"
+	// PhaseDriverPod creates or updates the Driver Pod
+	PhaseDriverPod SubmissionPhase = "driver-pod"
+	// PhaseService creates or updates the Driver Pod Service
+	PhaseService SubmissionPhase = "service"
+)
+
+// SubmissionResult describes what a native submission produced
+type SubmissionResult struct {
+	SparkApplicationID string
+	SubmissionID       string
+	ConfigMapName      string
+	DriverPodName      string
+	ServiceName        string
+	UIPort             int32
+	// PhaseDurations holds the time spent in each phase that was reached
+	PhaseDurations map[SubmissionPhase]time.Duration
+	// Operations records, per phase, whether the resource was created or updated. For PhaseAdditionalResources it
+	// holds the outcome of the last of the additional resources.
+	Operations map[SubmissionPhase]controllerutil.OperationResult
+}
+
+func newSubmissionResult(app *v1beta2.SparkApplication, rendered *RenderedResources) *SubmissionResult {
+	return &SubmissionResult{
+		SparkApplicationID: app.Status.SparkApplicationID,
+		SubmissionID:       app.Status.SubmissionID,
+		ConfigMapName:      rendered.ConfigMap.Name,
+		DriverPodName:      rendered.DriverPod.Name,
+		ServiceName:        rendered.Service.Name,
+		UIPort:             getUIPort(rendered.Service),
+		PhaseDurations:     make(map[SubmissionPhase]time.Duration),
+		Operations:         make(map[SubmissionPhase]controllerutil.OperationResult),
+	}
+}
+
+// recordPhase adds the duration of a phase that started at start, and observes it in the phase duration metric.
+// Phases writing several resources are recorded once per resource.
+func (r *SubmissionResult) recordPhase(phase SubmissionPhase, start time.Time) {
+	duration := time.Since(start)
+	r.PhaseDurations[phase] += duration
+	metrics.PhaseDuration.WithLabelValues(string(phase)).Observe(duration.Seconds())
+}
+
+// updateDriverInfo populates the Spark Application status the same way the operator's spark-submit path does.
+// The driver service is headless, so the UI address uses the service DNS name rather than a cluster IP.
+func (r *SubmissionResult) updateDriverInfo(app *v1beta2.SparkApplication) {
+	app.Status.DriverInfo.PodName = r.DriverPodName
+	app.Status.DriverInfo.WebUIServiceName = r.ServiceName
+	app.Status.DriverInfo.WebUIPort = r.UIPort
+	app.Status.DriverInfo.WebUIAddress = fmt.Sprintf("%s.%s.%s:%d", r.ServiceName, app.Namespace, service.ServiceShortForm, r.UIPort)
+}
+
+// getUIPort Helper func to get the Spark UI port exposed by the Driver Pod Service
+func getUIPort(driverPodService *apiv1.Service) int32 {
+	for _, port := range driverPodService.Spec.Ports {
+		if port.Name == service.UiPortName {
+			return port.Port
+		}
+	}
+	return service.UiPort
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
@@ -8,13 +9,15 @@ import (
 	"nativesubmit/internal/configmap"
 	"nativesubmit/internal/driver"
 	"nativesubmit/internal/service"
+	"nativesubmit/internal/tracing"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/go-logr/logr"
 	"github.com/kubeflow/spark-operator/api/v1beta2"
 	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 const (
@@ -38,6 +41,7 @@ const (
 	SparkDriverRole                = "driver"
 	SparkAppSubmissionIDAnnotation = "sparkoperator.k8s.io/submission-id"
 	SparkAppLauncherSOAnnotation   = "sparkoperator.k8s.io/launched-by-spark-operator"
+	SparkDriverPodTemplateFileKey  = "spark.kubernetes.driver.podTemplateFile"
 )
 
 // |      +-+--+----+    |    +-----v--+-+
@@ -51,32 +55,106 @@ const (
 // Logic involved in moving "New" Spark Application to "Submitted" state is implemented in Golang with this function RunAltSparkSubmit as starting step
 // 3 Resources are created in this logic per new Spark Application, in the order listed: ConfigMap for the Spark Application, Driver Pod, Driver Service
 
-func runAltSparkSubmitWrapper(app *v1beta2.SparkApplication, cl ctrlClient.Client) error {
-	_, err := runAltSparkSubmit(app, app.Status.SubmissionID, cl)
-	return err
+func (a *NativeSubmit) runAltSparkSubmitWrapper(ctx context.Context, app *v1beta2.SparkApplication, cl ctrlClient.Client) (*SubmissionResult, error) {
+	return a.runAltSparkSubmit(ctx, app, app.Status.SubmissionID, cl)
 }
 
-func runAltSparkSubmit(app *v1beta2.SparkApplication, submissionID string, kubeClient ctrlClient.Client) (bool, error) {
+func (a *NativeSubmit) runAltSparkSubmit(ctx context.Context, app *v1beta2.SparkApplication, submissionID string, kubeClient ctrlClient.Client) (result *SubmissionResult, err error) {
 	if app == nil {
-		return false, fmt.Errorf("spark application cannot be nil")
+		return nil, fmt.Errorf("spark application cannot be nil")
+	}
+	// phase is the phase to blame when the submission fails
+	phase := PhaseRender
+	submissionStart := time.Now()
+	defer func() {
+		recordSubmissionMetrics(app, phase, submissionStart, err)
+	}()
+
+	// The resource packages log through the logger of ctx, so their lines carry the same key/values
+	ctx = logr.NewContext(ctx, a.logger(ctx).WithValues("app", app.Name, "namespace", app.Namespace))
+	// Events are written in the background and may outlive the submission timeout, but not the caller's context
+	recorder, closeRecorder := a.eventRecorder(ctx, kubeClient)
+	defer closeRecorder()
+
+	if a.SubmissionTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.SubmissionTimeout)
+		defer cancel()
+	}
+	logr.FromContextOrDiscard(ctx).Info("Launching spark application")
+	phaseStart := time.Now()
+	// A retried submission reuses the identifiers of the resources it already created
+	identity, err := resolveSubmissionIdentity(ctx, app, submissionID, kubeClient, a.AllowOwnershipTakeover)
+	if err != nil {
+		err = fmt.Errorf("cannot submit spark application %s in namespace %s: %w", app.Name, app.Namespace, err)
+		recordRenderEvents(recorder, app, nil, err)
+		return nil, err
+	}
+	reportReplacement(ctx, recorder, app, identity)
+	rendered, err := renderResources(ctx, app, identity, a.featureSteps(), false)
+	if err == nil {
+		// The Spark ConfigMap of the application lives in the cluster, so it is merged after rendering
+		err = configmap.MergeSparkConfigMap(ctx, kubeClient, app, rendered.ConfigMap)
+	}
+	recordRenderEvents(recorder, app, rendered, err)
+	if err != nil {
+		return nil, err
+	}
+	// The generated Spark Application ID is known from here on
+	ctx = tracing.WithApplication(ctx, app)
+	ctx = withLogValues(ctx, "submissionID", app.Status.SubmissionID, "sparkApplicationID", app.Status.SparkApplicationID)
+	result = newSubmissionResult(app, rendered)
+	result.recordPhase(PhaseRender, phaseStart)
+
+	// Create resources, rolling back the ones already created if any of them fails. The Service does not depend on
+	// the other resources, so it is created alongside the ConfigMap and the Driver Pod that mounts it.
+	transaction := newSubmissionTransaction(ctx, kubeClient, recorder, app)
+	runner := newPhaseRunner(ctx, recorder, app, transaction, result)
+	runner.concurrently(
+		func() bool {
+			if !runner.run(PhaseConfigMap, rendered.ConfigMap, func(ctx context.Context) (controllerutil.OperationResult, error) {
+				return configmap.CreateOrUpdate(ctx, rendered.ConfigMap, kubeClient, a.submitOptions())
+			}) {
+				return false
+			}
+			// The resources contributed by the feature steps may be referenced by the Driver Pod
+			for _, obj := range rendered.AdditionalResources {
+				if !runner.run(PhaseAdditionalResources, obj, func(ctx context.Context) (controllerutil.OperationResult, error) {
+					return common.CreateOrUpdate(ctx, kubeClient, obj, resourceOf(obj), a.submitOptions())
+				}) {
+					return false
+				}
+			}
+			return runner.run(PhaseDriverPod, rendered.DriverPod, func(ctx context.Context) (controllerutil.OperationResult, error) {
+				return driver.CreateOrUpdate(ctx, rendered.DriverPod, kubeClient, a.submitOptions())
+			})
+		},
+		func() bool {
+			return runner.run(PhaseService, rendered.Service, func(ctx context.Context) (controllerutil.OperationResult, error) {
+				return service.CreateOrUpdate(ctx, rendered.Service, kubeClient, a.submitOptions())
+			})
+		},
+	)
+	if err := runner.err(); err != nil {
+		phase = runner.failedPhase
+		return nil, transaction.fail(err, a.KeepResourcesOnFailure)
 	}
 
-	appSpecVolumeMounts := app.Spec.Driver.VolumeMounts
-	appSpecVolumes := app.Spec.Volumes
-
-	// Create Application ID with the convention followed in Scala/Java
-	uuidString := strings.ReplaceAll(uuid.New().String(), "-", "")
-	createdApplicationId := fmt.Sprintf("%s-%s", Spark, uuidString)
-
-	//Update Application CRD Instance with Spark Application ID
-	app.Status.SparkApplicationID = createdApplicationId
-
-	//Create Spark Application ConfigMap Name with the convention followed in Scala/Java
-	driverConfigMapName := fmt.Sprintf("%s%s", common.GetDriverPodName(app), ConfigMapExtension)
-	serviceName := getServiceName(app)
+	result.updateDriverInfo(app)
+	logr.FromContextOrDiscard(ctx).Info("Submitted spark application", "driverPod", result.DriverPodName, "duration", time.Since(submissionStart))
+	return result, nil
+}
 
-	//Update Application CRD Instance with Submission ID
-	app.Status.SubmissionID = submissionID
+// getServiceLabels Helper func to get the labels shared by the Driver Pod and the selector of its Service
+func getServiceLabels(app *v1beta2.SparkApplication, submissionID string, createdApplicationId string) map[string]string {
+	serviceLabels := map[string]string{
+		SparkAppNameLabel:              app.Name,
+		SparkAppName:                   app.Name,
+		SparkApplicationSelectorLabel:  createdApplicationId,
+		SparkRoleLabel:                 SparkDriverRole,
+		SparkAppSubmissionIDAnnotation: submissionID,
+		SparkAppLauncherSOAnnotation:   True,
+	}
 
 	// Merge driver labels
 	if app.Spec.Driver.Labels != nil {
@@ -104,21 +182,7 @@ func runAltSparkSubmit(app *v1beta2.SparkApplication, submissionID string, kubeC
 			serviceLabels[labelKey] = sparkConfValue
 		}
 	}
-
-	// Create resources
-	if err := configmap.Create(app, submissionID, createdApplicationId, kubeClient, driverConfigMapName, serviceName); err != nil {
-		return false, fmt.Errorf("error while creating configmap %s in namespace %s: %w", driverConfigMapName, app.Namespace, err)
-	}
-
-	if err := driver.Create(app, serviceLabels, driverConfigMapName, kubeClient, appSpecVolumeMounts, appSpecVolumes); err != nil {
-		return false, fmt.Errorf("error while creating driver pod %s in namespace %s: %w", common.GetDriverPodName(app), app.Namespace, err)
-	}
-
-	if err := service.Create(app, serviceLabels, kubeClient, createdApplicationId, serviceName); err != nil {
-		return false, fmt.Errorf("error while creating driver service %s in namespace %s: %w", serviceName, app.Namespace, err)
-	}
-
-	return true, nil
+	return serviceLabels
 }
 
 // getServiceName Helper function to get Spark Application Driver Pod's Service Name
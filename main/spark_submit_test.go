@@ -1,12 +1,24 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"nativesubmit/common"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/kubeflow/spark-operator/api/v1beta2"
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 func TestRunAltSparkSubmit(t *testing.T) {
@@ -20,7 +32,11 @@ func TestRunAltSparkSubmit(t *testing.T) {
 		{
 			name: "valid spark application with submission ID",
 			app: &v1beta2.SparkApplication{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-app",
+				},
 				Spec: v1beta2.SparkApplicationSpec{
+					Image: common.StringPointer("spark:3.5.0"),
 					Driver: v1beta2.DriverSpec{
 						SparkPodSpec: v1beta2.SparkPodSpec{
 							Labels: map[string]string{
@@ -46,17 +62,135 @@ func TestRunAltSparkSubmit(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			cl := fake.NewClientBuilder().Build()
-			success, err := runAltSparkSubmit(tt.app, tt.submissionID, cl)
+			result, err := (&NativeSubmit{}).runAltSparkSubmit(context.TODO(), tt.app, tt.submissionID, cl)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
 			}
-			assert.Equal(t, tt.wantSuccess, success)
+			assert.Equal(t, tt.wantSuccess, result != nil)
 		})
 	}
 }
 
+// newTestSparkApplication Helper func to build the smallest Spark Application that passes validation
+func newTestSparkApplication(name string) *v1beta2.SparkApplication {
+	return &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Image: common.StringPointer("spark:3.5.0"),
+		},
+	}
+}
+
+func TestRunAltSparkSubmitResult(t *testing.T) {
+	app := newTestSparkApplication("test-app")
+	cl := fake.NewClientBuilder().Build()
+
+	result, err := (&NativeSubmit{}).runAltSparkSubmit(context.TODO(), app, "test-submission-id", cl)
+	assert.NoError(t, err)
+	assert.Equal(t, app.Status.SparkApplicationID, result.SparkApplicationID)
+	assert.Equal(t, "test-submission-id", result.SubmissionID)
+	assert.Equal(t, "test-app-driver-conf-map", result.ConfigMapName)
+	assert.Equal(t, "test-app-driver", result.DriverPodName)
+	assert.Equal(t, "test-app-driver-svc", result.ServiceName)
+	assert.Equal(t, int32(4040), result.UIPort)
+	for _, phase := range []SubmissionPhase{PhaseRender, PhaseConfigMap, PhaseDriverPod, PhaseService} {
+		assert.Contains(t, result.PhaseDurations, phase)
+	}
+	assert.Equal(t, controllerutil.OperationResultCreated, result.Operations[PhaseConfigMap])
+	assert.Equal(t, controllerutil.OperationResultCreated, result.Operations[PhaseDriverPod])
+	assert.Equal(t, controllerutil.OperationResultCreated, result.Operations[PhaseService])
+
+	assert.Equal(t, v1beta2.DriverInfo{
+		PodName:          "test-app-driver",
+		WebUIServiceName: "test-app-driver-svc",
+		WebUIPort:        4040,
+		WebUIAddress:     "test-app-driver-svc.default.svc:4040",
+	}, app.Status.DriverInfo)
+
+	// A second submission finds the ConfigMap and Service in place
+	result, err = (&NativeSubmit{}).runAltSparkSubmit(context.TODO(), app, "test-submission-id", cl)
+	assert.NoError(t, err)
+	assert.Equal(t, controllerutil.OperationResultUpdated, result.Operations[PhaseConfigMap])
+	assert.Equal(t, controllerutil.OperationResultUpdated, result.Operations[PhaseService])
+}
+
+func TestRunAltSparkSubmitSparkConfigMap(t *testing.T) {
+	app := newTestSparkApplication("test-app")
+	app.Spec.SparkConfigMap = common.StringPointer("spark-conf")
+	app.Spec.SparkConf = map[string]string{"spark.sql.shuffle.partitions": "10"}
+
+	// The Spark ConfigMap has to exist before the submission
+	_, err := (&NativeSubmit{}).runAltSparkSubmit(context.TODO(), app.DeepCopy(), "test-submission-id", fake.NewClientBuilder().Build())
+	assert.ErrorContains(t, err, "spark configmap spark-conf")
+
+	cl := fake.NewClientBuilder().WithObjects(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "spark-conf", Namespace: "default"},
+		Data:       map[string]string{"spark-defaults.conf": "spark.sql.shuffle.partitions=200\nspark.sql.catalogImplementation=hive\n"},
+	}).Build()
+	result, err := (&NativeSubmit{}).runAltSparkSubmit(context.TODO(), app, "test-submission-id", cl)
+	assert.NoError(t, err)
+
+	configMap := &corev1.ConfigMap{}
+	assert.NoError(t, cl.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: result.ConfigMapName}, configMap))
+	properties := configMap.Data["spark.properties"]
+	assert.Contains(t, properties, "spark.sql.catalogImplementation=hive\n")
+	assert.Contains(t, properties, "spark.sql.shuffle.partitions=10\n")
+	assert.NotContains(t, properties, "spark.sql.shuffle.partitions=200")
+}
+
+func TestRunAltSparkSubmitServerSideApply(t *testing.T) {
+	// Resources are applied concurrently
+	var mu sync.Mutex
+	var appliedKinds []string
+	// The fake client does not implement server-side apply, applies are recorded and emulated with a create or an update
+	cl := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			if patch.Type() != types.ApplyPatchType {
+				return c.Patch(ctx, obj, patch, opts...)
+			}
+			patchOptions := &client.PatchOptions{}
+			patchOptions.ApplyOptions(opts)
+			assert.Equal(t, common.FieldManager, patchOptions.FieldManager)
+			assert.True(t, *patchOptions.Force)
+			mu.Lock()
+			appliedKinds = append(appliedKinds, obj.GetObjectKind().GroupVersionKind().Kind)
+			mu.Unlock()
+
+			existing := obj.DeepCopyObject().(client.Object)
+			if err := c.Get(ctx, client.ObjectKeyFromObject(obj), existing); apiErrors.IsNotFound(err) {
+				return c.Create(ctx, obj)
+			}
+			obj.SetResourceVersion(existing.GetResourceVersion())
+			return c.Update(ctx, obj)
+		},
+		Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+			if _, isPod := obj.(*corev1.Pod); isPod {
+				t.Errorf("driver pod must not be updated")
+			}
+			return c.Update(ctx, obj, opts...)
+		},
+	}).Build()
+	nativeSubmit := &NativeSubmit{ApplyMode: common.ApplyModeServerSide}
+
+	result, err := nativeSubmit.runAltSparkSubmit(context.TODO(), newTestSparkApplication("test-app"), "test-submission-id", cl)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"ConfigMap", "Pod", "Service"}, appliedKinds)
+	assert.Equal(t, controllerutil.OperationResultCreated, result.Operations[PhaseConfigMap])
+	assert.Equal(t, controllerutil.OperationResultCreated, result.Operations[PhaseDriverPod])
+	assert.Equal(t, controllerutil.OperationResultCreated, result.Operations[PhaseService])
+
+	// A resubmission re-applies the configmap and service, and reuses the driver pod of the same submission
+	result, err = nativeSubmit.runAltSparkSubmit(context.TODO(), newTestSparkApplication("test-app"), "test-submission-id", cl)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"ConfigMap", "Pod", "Service", "ConfigMap", "Service"}, appliedKinds)
+	assert.Equal(t, controllerutil.OperationResultNone, result.Operations[PhaseDriverPod])
+}
+
 func TestGetServiceName(t *testing.T) {
 	tests := []struct {
 		name string
@@ -119,3 +253,33 @@ func TestRandomHex(t *testing.T) {
 		})
 	}
 }
+
+// newLatencyClient Helper func to build a fake client whose API calls take apiLatency, like calls to a real API server
+func newLatencyClient(apiLatency time.Duration) client.Client {
+	return fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			time.Sleep(apiLatency)
+			return c.Get(ctx, key, obj, opts...)
+		},
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			time.Sleep(apiLatency)
+			return c.List(ctx, list, opts...)
+		},
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			time.Sleep(apiLatency)
+			return c.Create(ctx, obj, opts...)
+		},
+	}).Build()
+}
+
+// BenchmarkRunAltSparkSubmit measures the end-to-end latency of a submission against an API server answering every
+// call after 5ms
+func BenchmarkRunAltSparkSubmit(b *testing.B) {
+	nativeSubmit := &NativeSubmit{EventRecorder: record.NewFakeRecorder(b.N * 10)}
+	for i := 0; i < b.N; i++ {
+		app := newTestSparkApplication(fmt.Sprintf("bench-app-%d", i))
+		if _, err := nativeSubmit.runAltSparkSubmit(context.TODO(), app, "bench-submission-id", newLatencyClient(5*time.Millisecond)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"nativesubmit/internal/tracing"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// installTestTracerProvider Helper func to record the spans of a test in memory
+func installTestTracerProvider(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return exporter
+}
+
+// spanAttributes Helper func to index the attributes of a span by key
+func spanAttributes(span tracetest.SpanStub) map[attribute.Key]string {
+	attributes := make(map[attribute.Key]string)
+	for _, kv := range span.Attributes {
+		attributes[kv.Key] = kv.Value.Emit()
+	}
+	return attributes
+}
+
+func TestLaunchSparkApplicationTracing(t *testing.T) {
+	exporter := installTestTracerProvider(t)
+	const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	app := newTestSparkApplication("test-app")
+	app.Annotations = map[string]string{tracing.TraceParentAnnotation: traceParent}
+	app.Status.SubmissionID = "test-submission-id"
+	nativeSubmit := &NativeSubmit{EventRecorder: record.NewFakeRecorder(100)}
+	_, err := nativeSubmit.LaunchSparkApplicationWithResult(context.TODO(), app, fake.NewClientBuilder().Build())
+	assert.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	spanNames := make([]string, 0, len(spans))
+	spansByName := make(map[string]tracetest.SpanStub)
+	for _, span := range spans {
+		spanNames = append(spanNames, span.Name)
+		spansByName[span.Name] = span
+	}
+	assert.ElementsMatch(t, []string{"configmap.CreateOrUpdate", "driver.CreateOrUpdate", "service.CreateOrUpdate", "LaunchSparkApplication"}, spanNames)
+
+	launchSpan := spansByName["LaunchSparkApplication"]
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", launchSpan.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", launchSpan.Parent.SpanID().String())
+	assert.True(t, launchSpan.Parent.IsRemote())
+
+	for _, span := range spans {
+		assert.Equal(t, launchSpan.SpanContext.TraceID(), span.SpanContext.TraceID(), span.Name)
+		if span.Name != "LaunchSparkApplication" {
+			assert.Equal(t, launchSpan.SpanContext.SpanID(), span.Parent.SpanID(), span.Name)
+		}
+		attributes := spanAttributes(span)
+		assert.Equal(t, "test-app", attributes[tracing.AttributeAppName], span.Name)
+		assert.Equal(t, "default", attributes[tracing.AttributeNamespace], span.Name)
+		assert.Equal(t, "test-submission-id", attributes[tracing.AttributeSubmissionID], span.Name)
+		assert.Equal(t, app.Status.SparkApplicationID, attributes[tracing.AttributeSparkApplicationID], span.Name)
+	}
+	assert.Equal(t, "test-app-driver", spanAttributes(spansByName["driver.CreateOrUpdate"])["k8s.pod.name"])
+}
+
+func TestLaunchSparkApplicationTracingRecordsFailure(t *testing.T) {
+	exporter := installTestTracerProvider(t)
+
+	app := newTestSparkApplication("test-app")
+	app.Annotations = map[string]string{tracing.TraceParentAnnotation: "not-a-traceparent"}
+	app.Spec.Image = nil
+	nativeSubmit := &NativeSubmit{EventRecorder: record.NewFakeRecorder(100)}
+	_, err := nativeSubmit.LaunchSparkApplicationWithResult(context.TODO(), app, fake.NewClientBuilder().Build())
+	assert.Error(t, err)
+
+	spans := exporter.GetSpans()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "LaunchSparkApplication", spans[0].Name)
+		assert.False(t, spans[0].Parent.IsValid())
+		assert.Equal(t, codes.Error, spans[0].Status.Code)
+		assert.Len(t, spans[0].Events, 1)
+	}
+}
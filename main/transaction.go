@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"nativesubmit/internal/events"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/kubeflow/spark-operator/api/v1beta2"
+	apiv1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
+	ctrlClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// submissionTransaction tracks the resources created during a submission, so that a failed submission
+// does not leave a partial set of resources behind. Resources that already existed and were only updated are
+// not tracked, as deleting them would destroy state this submission does not own.
+type submissionTransaction struct {
+	ctx        context.Context
+	kubeClient ctrlClient.Client
+	// recorder and app receive the event reporting the rollback
+	recorder record.EventRecorder
+	app      *v1beta2.SparkApplication
+	created  []ctrlClient.Object
+}
+
+// rollbackTimeout bounds the rollback, which keeps running when the submission itself was cancelled or timed out
+const rollbackTimeout = 30 * time.Second
+
+func newSubmissionTransaction(ctx context.Context, kubeClient ctrlClient.Client, recorder record.EventRecorder, app *v1beta2.SparkApplication) *submissionTransaction {
+	return &submissionTransaction{ctx: ctx, kubeClient: kubeClient, recorder: recorder, app: app}
+}
+
+// track records obj when the API call that produced operationResult created it
+func (t *submissionTransaction) track(obj ctrlClient.Object, operationResult controllerutil.OperationResult) {
+	if operationResult == controllerutil.OperationResultCreated {
+		t.created = append(t.created, obj)
+	}
+}
+
+// fail wraps the submission error with the outcome of the rollback. The created resources are deleted in reverse
+// creation order unless keepOnFailure is set, in which case they are left in place for debugging.
+func (t *submissionTransaction) fail(submitErr error, keepOnFailure bool) error {
+	if len(t.created) == 0 {
+		t.recorder.Event(t.app, apiv1.EventTypeWarning, events.ReasonSubmissionFailed, submitErr.Error())
+		return submitErr
+	}
+	if keepOnFailure {
+		t.recorder.Eventf(t.app, apiv1.EventTypeWarning, events.ReasonResourcesKept,
+			"Kept %s of the failed submission for debugging: %v", describeResources(t.created), submitErr)
+		return fmt.Errorf("%w; kept on failure: %s", submitErr, describeResources(t.created))
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(t.ctx), rollbackTimeout)
+	defer cancel()
+
+	var rolledBack, notRolledBack []ctrlClient.Object
+	var rollbackErrs []string
+	for index := len(t.created) - 1; index >= 0; index-- {
+		obj := t.created[index]
+		err := t.kubeClient.Delete(ctx, obj, ctrlClient.PropagationPolicy("Background"))
+		if err != nil && !apiErrors.IsNotFound(err) {
+			notRolledBack = append(notRolledBack, obj)
+			rollbackErrs = append(rollbackErrs, err.Error())
+			continue
+		}
+		rolledBack = append(rolledBack, obj)
+	}
+
+	rollbackErr := fmt.Errorf("%w; rolled back: %s", submitErr, describeResources(rolledBack))
+	if len(notRolledBack) > 0 {
+		rollbackErr = fmt.Errorf("%w; failed to roll back: %s (%s)", rollbackErr, describeResources(notRolledBack), strings.Join(rollbackErrs, "; "))
+	}
+	t.recorder.Eventf(t.app, apiv1.EventTypeWarning, events.ReasonRolledBack, "Rolled back %s of the failed submission: %v",
+		describeResources(rolledBack), rollbackErr)
+	return rollbackErr
+}
+
+// describeResources formats resources as Kind namespace/name, in the given order
+func describeResources(objs []ctrlClient.Object) string {
+	if len(objs) == 0 {
+		return "none"
+	}
+	descriptions := make([]string, 0, len(objs))
+	for _, obj := range objs {
+		// TypeMeta is not reliably populated on typed objects returned by the client
+		kind := reflect.TypeOf(obj).Elem().Name()
+		descriptions = append(descriptions, fmt.Sprintf("%s %s/%s", kind, obj.GetNamespace(), obj.GetName()))
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+// resourceOf Helper func to get the plural lower case resource name of a typed object, e.g. configmaps
+func resourceOf(obj ctrlClient.Object) string {
+	return strings.ToLower(reflect.TypeOf(obj).Elem().Name()) + "s"
+}
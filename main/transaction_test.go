@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kubeflow/spark-operator/api/v1beta2"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestRunAltSparkSubmitRollback(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = v1beta2.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	tests := []struct {
+		name          string
+		keepOnFailure bool
+		wantErr       string
+		wantRemaining bool
+	}{
+		{
+			name:          "created resources are rolled back in reverse order",
+			keepOnFailure: false,
+			wantErr:       "rolled back: Pod default/test-app-driver, ConfigMap default/test-app-driver-conf-map",
+			wantRemaining: false,
+		},
+		{
+			name:          "created resources are kept on failure",
+			keepOnFailure: true,
+			wantErr:       "kept on failure: ConfigMap default/test-app-driver-conf-map, Pod default/test-app-driver",
+			wantRemaining: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cl := fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(interceptor.Funcs{
+				Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+					if _, isService := obj.(*corev1.Service); isService {
+						return errors.New("service quota exceeded")
+					}
+					return c.Create(ctx, obj, opts...)
+				},
+			}).Build()
+			app := newTestSparkApplication("test-app")
+
+			ns := &NativeSubmit{KeepResourcesOnFailure: tt.keepOnFailure}
+			result, err := ns.runAltSparkSubmit(context.TODO(), app, "test-submission-id", cl)
+			assert.Nil(t, result)
+			assert.ErrorContains(t, err, "service quota exceeded")
+			assert.ErrorContains(t, err, tt.wantErr)
+
+			configMapErr := cl.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: "test-app-driver-conf-map"}, &corev1.ConfigMap{})
+			podErr := cl.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: "test-app-driver"}, &corev1.Pod{})
+			if tt.wantRemaining {
+				assert.NoError(t, configMapErr)
+				assert.NoError(t, podErr)
+			} else {
+				assert.True(t, apiErrors.IsNotFound(configMapErr))
+				assert.True(t, apiErrors.IsNotFound(podErr))
+			}
+		})
+	}
+}
+
+func TestSubmissionTransactionSkipsUpdatedResources(t *testing.T) {
+	existing := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "default"}}
+	cl := fake.NewClientBuilder().WithObjects(existing).Build()
+
+	transaction := newSubmissionTransaction(context.TODO(), cl, record.NewFakeRecorder(10), newTestSparkApplication("test-app"))
+	transaction.track(existing, "updated")
+	err := transaction.fail(errors.New("submission failed"), false)
+	assert.EqualError(t, err, "submission failed")
+	assert.NoError(t, cl.Get(context.TODO(), client.ObjectKeyFromObject(existing), &corev1.ConfigMap{}))
+}